@@ -0,0 +1,339 @@
+// Package batch runs many independent one-shot queries with bounded
+// concurrency and rate, collecting the result, cost and error of each.
+//
+// A Runner can record finished items in a checkpoint file. Running the same
+// items again with the same checkpoint skips those that already succeeded,
+// so a batch interrupted by a crash resumes where it stopped.
+//
+// Example:
+//
+//	runner := &batch.Runner{
+//	    Concurrency:   8,
+//	    RatePerSecond: 2,
+//	    Checkpoint:    "reviews.checkpoint.jsonl",
+//	    Options:       &claude.AgentOptions{MaxTurns: claude.Int(3)},
+//	}
+//
+//	items := make([]batch.Item, len(files))
+//	for i, file := range files {
+//	    items[i] = batch.Item{ID: file, Prompt: "Review " + file}
+//	}
+//
+//	results, err := runner.Run(ctx, items)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	for _, r := range results {
+//	    if r.Err != nil {
+//	        log.Printf("%s failed: %v", r.ID, r.Err)
+//	    }
+//	}
+package batch
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	claude "github.com/nabkey/claude-agent-sdk-go"
+	"github.com/nabkey/claude-agent-sdk-go/cost"
+	"github.com/nabkey/claude-agent-sdk-go/types"
+)
+
+// defaultConcurrency is the number of workers used when Concurrency is zero.
+const defaultConcurrency = 4
+
+// Item is one prompt of a batch.
+type Item struct {
+	// ID identifies the item in results and in the checkpoint. It must be
+	// unique within the batch; Run uses the item's index when it is empty.
+	ID string
+	// Prompt is the prompt sent to Claude.
+	Prompt string
+	// Options overrides the Runner's options for this item.
+	Options *claude.AgentOptions
+}
+
+// Result is the outcome of one item.
+type Result struct {
+	// ID is the item's ID.
+	ID string
+	// Result is the item's ResultMessage, if one was received.
+	Result *types.ResultMessage
+	// CostUSD is the cost reported for the item.
+	CostUSD float64
+	// Duration is how long the query ran.
+	Duration time.Duration
+	// Err is the error that failed the item, if any.
+	Err error
+	// Resumed is set when the result was read from the checkpoint instead
+	// of running the query.
+	Resumed bool
+}
+
+// Runner runs batches of queries. Its fields must not be changed while a
+// batch is running.
+type Runner struct {
+	// Concurrency limits the queries running at once. Zero means 4.
+	Concurrency int
+
+	// RatePerSecond limits how many queries start per second. Zero means
+	// no limit.
+	RatePerSecond float64
+
+	// Options are the options of items that do not set their own.
+	Options *claude.AgentOptions
+
+	// Checkpoint is the path of a file recording succeeded items. Items
+	// found in it are not run again. Empty disables checkpointing.
+	Checkpoint string
+
+	// Tracker, if set, records the results of all items.
+	Tracker *cost.Tracker
+
+	// OnResult is called as each item finishes, from the worker that ran it.
+	OnResult func(Result)
+}
+
+// Run runs items and returns their results in the same order. It returns an
+// error if the checkpoint cannot be used or ctx is cancelled; items that did
+// not run then have their Err set to the context's error.
+func (r *Runner) Run(ctx context.Context, items []Item) ([]Result, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	index := make(map[string]int, len(items))
+	results := make([]Result, len(items))
+	for i, item := range items {
+		id := item.ID
+		if id == "" {
+			id = strconv.Itoa(i)
+		}
+		if _, dup := index[id]; dup {
+			return nil, fmt.Errorf("duplicate batch item ID %q", id)
+		}
+		index[id] = i
+		results[i] = Result{ID: id}
+	}
+
+	ch := make(chan Item)
+	go func() {
+		defer close(ch)
+		for i, item := range items {
+			if item.ID == "" {
+				item.ID = strconv.Itoa(i)
+			}
+			select {
+			case ch <- item:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	done := make([]bool, len(items))
+	out, errc := r.run(ctx, ch)
+	for result := range out {
+		i := index[result.ID]
+		results[i] = result
+		done[i] = true
+	}
+
+	err := <-errc
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		for i := range results {
+			if !done[i] {
+				results[i].Err = err
+			}
+		}
+	}
+	return results, err
+}
+
+// RunChan runs items as they arrive on the channel and sends each result on
+// the returned channel, in completion order. Items must have an ID. The
+// result channel is closed once items is closed and drained, or ctx is
+// cancelled; the error channel then yields the checkpoint error, if any.
+func (r *Runner) RunChan(ctx context.Context, items <-chan Item) (<-chan Result, <-chan error) {
+	return r.run(ctx, items)
+}
+
+// run is the worker pool shared by Run and RunChan.
+func (r *Runner) run(ctx context.Context, items <-chan Item) (<-chan Result, <-chan error) {
+	out := make(chan Result)
+	errc := make(chan error, 1)
+
+	var cp *checkpoint
+	if r.Checkpoint != "" {
+		var err error
+		if cp, err = openCheckpoint(r.Checkpoint); err != nil {
+			close(out)
+			errc <- err
+			close(errc)
+			return out, errc
+		}
+	}
+
+	workers := r.Concurrency
+	if workers <= 0 {
+		workers = defaultConcurrency
+	}
+	limiter := newRateLimiter(r.RatePerSecond)
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		errOnce.Do(func() { firstErr = err })
+	}
+
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				var item Item
+				var ok bool
+				select {
+				case item, ok = <-items:
+				case <-ctx.Done():
+					return
+				}
+				if !ok {
+					return
+				}
+
+				result, resumed := cp.lookup(item.ID)
+				if !resumed {
+					if err := limiter.wait(ctx); err != nil {
+						return
+					}
+					result = r.runItem(ctx, item)
+					if ctx.Err() != nil && result.Err != nil {
+						// Cancelled items are reported by Run, not recorded
+						return
+					}
+					if result.Err == nil {
+						if err := cp.record(result); err != nil {
+							fail(err)
+						}
+					}
+					if r.Tracker != nil && result.Result != nil {
+						r.Tracker.Record(result.Result)
+					}
+				}
+
+				if r.OnResult != nil {
+					r.OnResult(result)
+				}
+				select {
+				case out <- result:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		if err := cp.close(); err != nil {
+			fail(err)
+		}
+		close(out)
+		errc <- firstErr
+		close(errc)
+	}()
+
+	return out, errc
+}
+
+// runItem runs one query and collects its outcome.
+func (r *Runner) runItem(ctx context.Context, item Item) Result {
+	options := item.Options
+	if options == nil {
+		options = r.Options
+	}
+
+	result := Result{ID: item.ID}
+	start := time.Now()
+
+	for msg, err := range claude.QuerySeq(ctx, item.Prompt, options) {
+		if err != nil {
+			result.Err = err
+			continue
+		}
+		if res, ok := msg.(*types.ResultMessage); ok {
+			result.Result = res
+		}
+	}
+	result.Duration = time.Since(start)
+
+	if res := result.Result; res != nil {
+		if res.TotalCostUSD != nil {
+			result.CostUSD = *res.TotalCostUSD
+		}
+		if res.IsError && result.Err == nil {
+			result.Err = resultError(res)
+		}
+	} else if result.Err == nil {
+		result.Err = fmt.Errorf("query ended without a result")
+	}
+	return result
+}
+
+// resultError describes a ResultMessage that reports a failure.
+func resultError(res *types.ResultMessage) error {
+	if res.Result != nil && *res.Result != "" {
+		return fmt.Errorf("query failed (%s): %s", res.Subtype, *res.Result)
+	}
+	return fmt.Errorf("query failed (%s)", res.Subtype)
+}
+
+// rateLimiter spaces out query starts evenly.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newRateLimiter(perSecond float64) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// wait blocks until the caller may start a query.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	start := l.next
+	if start.Before(now) {
+		start = now
+	}
+	l.next = start.Add(l.interval)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(start))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,133 @@
+package batch
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/nabkey/claude-agent-sdk-go/types"
+)
+
+// checkpointEntry is one line of a checkpoint file.
+type checkpointEntry struct {
+	ID         string               `json:"id"`
+	Result     *types.ResultMessage `json:"result,omitempty"`
+	CostUSD    float64              `json:"cost_usd"`
+	DurationMS int64                `json:"duration_ms"`
+}
+
+// checkpoint is an append-only JSON Lines file of succeeded items. A nil
+// checkpoint records nothing.
+type checkpoint struct {
+	mu      sync.Mutex
+	file    *os.File
+	entries map[string]checkpointEntry
+}
+
+// openCheckpoint loads the entries of path and opens it for appending. A
+// truncated last line, left by a crash during a write, is ignored.
+func openCheckpoint(path string) (*checkpoint, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open checkpoint: %w", err)
+	}
+
+	cp := &checkpoint{file: file, entries: make(map[string]checkpointEntry)}
+
+	reader := bufio.NewReader(file)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			_ = file.Close()
+			return nil, fmt.Errorf("read checkpoint: %w", err)
+		}
+
+		var entry checkpointEntry
+		if err := json.Unmarshal(line, &entry); err != nil || entry.ID == "" {
+			_ = file.Close()
+			return nil, fmt.Errorf("corrupt checkpoint line at offset %d", offset)
+		}
+		cp.entries[entry.ID] = entry
+		offset += int64(len(line))
+	}
+
+	// Drop a partial trailing line so that new entries start on their own line
+	if err := file.Truncate(offset); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("truncate checkpoint: %w", err)
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("seek checkpoint: %w", err)
+	}
+	return cp, nil
+}
+
+// lookup returns the recorded result of an item.
+func (cp *checkpoint) lookup(id string) (Result, bool) {
+	if cp == nil {
+		return Result{}, false
+	}
+
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	entry, ok := cp.entries[id]
+	if !ok {
+		return Result{}, false
+	}
+	return Result{
+		ID:       entry.ID,
+		Result:   entry.Result,
+		CostUSD:  entry.CostUSD,
+		Duration: time.Duration(entry.DurationMS) * time.Millisecond,
+		Resumed:  true,
+	}, true
+}
+
+// record appends a succeeded item and syncs the file.
+func (cp *checkpoint) record(result Result) error {
+	if cp == nil {
+		return nil
+	}
+
+	entry := checkpointEntry{
+		ID:         result.ID,
+		Result:     result.Result,
+		CostUSD:    result.CostUSD,
+		DurationMS: result.Duration.Milliseconds(),
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encode checkpoint entry: %w", err)
+	}
+
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	if _, err := cp.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+	if err := cp.file.Sync(); err != nil {
+		return fmt.Errorf("sync checkpoint: %w", err)
+	}
+	cp.entries[entry.ID] = entry
+	return nil
+}
+
+// close closes the checkpoint file.
+func (cp *checkpoint) close() error {
+	if cp == nil {
+		return nil
+	}
+	return cp.file.Close()
+}
@@ -4,8 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"iter"
 	"sync"
+	"sync/atomic"
 
+	"github.com/nabkey/claude-agent-sdk-go/cost"
 	"github.com/nabkey/claude-agent-sdk-go/errors"
 	"github.com/nabkey/claude-agent-sdk-go/internal/protocol"
 	"github.com/nabkey/claude-agent-sdk-go/internal/transport"
@@ -46,7 +49,25 @@ type Client struct {
 	mu        sync.Mutex
 
 	// Internal message handling
-	rawMsgChan <-chan map[string]any // Raw messages from query
+	router   *router             // Routes parsed messages to turns
+	sessions map[string]*Session // Sessions with their own process
+
+	// release ends the lifetime context of a process started by startClient
+	release context.CancelFunc
+
+	// Terminal status of the connection
+	done chan struct{}
+	stop chan struct{} // closed by Close to abandon reconnecting
+	err  error
+
+	// Shared budget accounting
+	meter             *cost.Meter
+	budgetInterrupted atomic.Bool
+
+	// Input streaming
+	controlProtocol bool // hooks, canUseTool or SDK MCP servers are in use
+	cancelInput     context.CancelFunc
+	inputErr        error
 }
 
 // NewClient creates a new Claude SDK client with the given options.
@@ -100,8 +121,46 @@ func (c *Client) Connect(ctx context.Context, prompt string) error {
 		return fmt.Errorf("can_use_tool callback cannot be used with permission_prompt_tool_name")
 	}
 
-	// Clone options and set up for control protocol if needed
+	if c.options.Budget != nil {
+		if err := c.options.Budget.Check(); err != nil {
+			return err
+		}
+	}
+
 	opts := c.options.Clone()
+	trans, q, err := c.open(ctx, opts)
+	if err != nil {
+		return err
+	}
+	c.transport = trans
+	c.query = q
+
+	c.router = newRouter()
+	c.router.interrupt = c.Interrupt
+	c.done = make(chan struct{})
+	c.stop = make(chan struct{})
+	c.err = nil
+	go c.route(c.router, q, c.done)
+
+	if opts.Budget != nil {
+		c.meter = opts.Budget.Meter()
+	}
+
+	c.controlProtocol = opts.CanUseTool != nil || len(opts.Hooks) > 0 || len(sdkMCPServers(opts)) > 0
+	c.connected = true
+
+	if prompt != "" {
+		data, err := json.Marshal(newUserInput(prompt))
+		if err != nil {
+			return err
+		}
+		return c.transport.Write(ctx, string(data)+"\n")
+	}
+	return nil
+}
+
+// open starts a CLI process with opts and initializes the control protocol.
+func (c *Client) open(ctx context.Context, opts *AgentOptions) (transport.Transport, *protocol.Query, error) {
 	// Enable control protocol for canUseTool or hooks
 	if opts.CanUseTool != nil || len(opts.Hooks) > 0 {
 		permTool := "stdio"
@@ -116,7 +175,7 @@ func (c *Client) Connect(ctx context.Context, prompt string) error {
 		AllowedTools:             opts.AllowedTools,
 		DisallowedTools:          opts.DisallowedTools,
 		MaxTurns:                 opts.MaxTurns,
-		MaxBudgetUSD:             opts.MaxBudgetUSD,
+		MaxBudgetUSD:             opts.maxBudgetUSD(),
 		Model:                    opts.Model,
 		FallbackModel:            opts.FallbackModel,
 		PermissionMode:           opts.PermissionMode,
@@ -146,32 +205,20 @@ func (c *Client) Connect(ctx context.Context, prompt string) error {
 	}
 
 	// Create transport (always streaming mode for Client)
-	var err error
-	c.transport, err = transport.NewSubprocessTransport(prompt, true, transportOpts)
+	trans, err := newTransport(transportOpts)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	// Connect transport
-	if err := c.transport.Connect(ctx); err != nil {
-		return err
-	}
-
-	// Extract SDK MCP servers
-	sdkServers := make(map[string]*protocol.MCPServerHandler)
-	if opts.MCPServers != nil {
-		for name, config := range opts.MCPServers {
-			if sdkConfig, ok := config.(*types.SDKMCPServer); ok {
-				if handler, ok := sdkConfig.Instance.(*protocol.MCPServerHandler); ok {
-					sdkServers[name] = handler
-				}
-			}
-		}
+	if err := trans.Connect(ctx); err != nil {
+		_ = trans.Close()
+		return nil, nil, err
 	}
 
 	// Create query handler
-	c.query = protocol.NewQuery(&protocol.QueryOptions{
-		Transport:       c.transport,
+	q := protocol.NewQuery(&protocol.QueryOptions{
+		Transport:       trans,
 		IsStreamingMode: true,
 		CanUseTool: func(ctx context.Context, toolName string, input map[string]any, permCtx types.ToolPermissionContext) (types.PermissionResult, error) {
 			if opts.CanUseTool == nil {
@@ -179,63 +226,235 @@ func (c *Client) Connect(ctx context.Context, prompt string) error {
 			}
 			return opts.CanUseTool(ctx, toolName, input, permCtx)
 		},
-		Hooks:         opts.Hooks,
-		SDKMCPServers: sdkServers,
+		Hooks:             opts.Hooks,
+		SDKMCPServers:     sdkMCPServers(opts),
+		OnHookTimeout:     opts.OnHookTimeout,
+		OnAsyncHookResult: opts.OnAsyncHookResult,
 	})
 
 	// Start reading messages
-	c.query.Start(ctx)
+	q.Start(ctx)
 
 	// Initialize control protocol
-	if _, err := c.query.Initialize(ctx); err != nil {
-		_ = c.transport.Close()
+	if _, err := q.Initialize(ctx); err != nil {
+		_ = q.Close()
+		return nil, nil, err
+	}
+
+	return trans, q, nil
+}
+
+// newTransport creates the streaming-mode transport of a client. Tests
+// replace it to run without a CLI.
+var newTransport = func(opts *transport.SubprocessOptions) (transport.Transport, error) {
+	return transport.NewSubprocessTransport("", true, opts)
+}
+
+// sdkMCPServers extracts the in-process MCP servers from opts.
+func sdkMCPServers(opts *AgentOptions) map[string]*protocol.MCPServerHandler {
+	sdkServers := make(map[string]*protocol.MCPServerHandler)
+	for name, config := range opts.MCPServers {
+		if sdkConfig, ok := config.(*types.SDKMCPServer); ok {
+			if handler, ok := sdkConfig.Instance.(*protocol.MCPServerHandler); ok {
+				sdkServers[name] = handler
+			}
+		}
+	}
+	return sdkServers
+}
+
+// ConnectStream establishes a connection and sends every message yielded by
+// input to Claude, in order, from a background goroutine. The next message
+// is only requested once the previous one has been written, so a slow CLI
+// applies backpressure to the producer. When input is exhausted, stdin is
+// closed; if hooks, a CanUseTool callback or SDK MCP servers are configured,
+// this waits for the first result so that control requests can still be
+// answered. Use ChannelInput to stream from a channel.
+//
+// Example:
+//
+//	prompts := make(chan types.UserInputMessage)
+//	go func() {
+//	    defer close(prompts)
+//	    prompts <- claude.NewUserMessage("List the files in this directory")
+//	    prompts <- claude.NewUserMessage("Now summarize the README")
+//	}()
+//
+//	if err := client.ConnectStream(ctx, claude.ChannelInput(prompts)); err != nil {
+//	    log.Fatal(err)
+//	}
+//	for msg := range client.ReceiveMessages() {
+//	    // Process messages...
+//	}
+func (c *Client) ConnectStream(ctx context.Context, input iter.Seq[types.UserInputMessage]) error {
+	if err := c.Connect(ctx, ""); err != nil {
 		return err
 	}
 
-	// Store the raw message channel for reading
-	c.rawMsgChan = c.query.ReceiveMessages()
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	c.connected = true
+	if c.cancelInput != nil {
+		return fmt.Errorf("input stream already attached")
+	}
+
+	inputCtx, cancel := context.WithCancel(context.Background())
+	c.cancelInput = cancel
+	go c.pumpInput(inputCtx, input, c.transport, c.query, c.controlProtocol)
 	return nil
 }
 
-// SendQuery sends a new query to Claude.
+// ChannelInput adapts a channel of user messages for ConnectStream. The
+// stream ends when the channel is closed.
+func ChannelInput(ch <-chan types.UserInputMessage) iter.Seq[types.UserInputMessage] {
+	return func(yield func(types.UserInputMessage) bool) {
+		for msg := range ch {
+			if !yield(msg) {
+				return
+			}
+		}
+	}
+}
+
+// pumpInput writes messages from input to the transport until input is
+// exhausted, ctx is cancelled or a write fails.
+func (c *Client) pumpInput(
+	ctx context.Context,
+	input iter.Seq[types.UserInputMessage],
+	t transport.Transport,
+	q *protocol.Query,
+	waitForResult bool,
+) {
+	for msg := range input {
+		if ctx.Err() != nil {
+			return
+		}
+		if c.options.Budget != nil {
+			if err := c.options.Budget.Check(); err != nil {
+				c.setInputErr(err)
+				return
+			}
+		}
+
+		if msg.Type == "" {
+			msg.Type = "user"
+		}
+		if msg.Message.Role == "" {
+			msg.Message.Role = "user"
+		}
+		if msg.SessionID == "" {
+			msg.SessionID = "default"
+		}
+
+		data, err := json.Marshal(msg)
+		if err != nil {
+			c.setInputErr(err)
+			return
+		}
+		if err := t.Write(ctx, string(data)+"\n"); err != nil {
+			c.setInputErr(err)
+			return
+		}
+	}
+
+	if waitForResult {
+		_ = q.WaitForFirstResult(ctx)
+	}
+	if ctx.Err() == nil {
+		_ = t.EndInput()
+	}
+}
+
+// setInputErr records the error that stopped the input stream.
+func (c *Client) setInputErr(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.inputErr == nil {
+		c.inputErr = err
+	}
+}
+
+// SendQuery sends a new query to Claude in the default session and returns
+// the Turn that receives its response. Use Session for other conversations.
+//
+// Example:
+//
+//	turn, err := client.SendQuery(ctx, "What is 2 + 2?")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	result, err := turn.Wait(ctx)
+func (c *Client) SendQuery(ctx context.Context, prompt string) (*Turn, error) {
+	return c.send(ctx, prompt)
+}
+
+// SendMessage sends a new query made of content blocks: text, images,
+// documents and tool results. Use NewImageFile, NewDocumentFile and the
+// related helpers to build image and document blocks from files.
 //
 // Example:
 //
-//	if err := client.SendQuery(ctx, "What is 2 + 2?"); err != nil {
+//	screenshot, err := claude.NewImageFile("screenshot.png")
+//	if err != nil {
 //	    log.Fatal(err)
 //	}
-func (c *Client) SendQuery(ctx context.Context, prompt string) error {
+//	turn, err := client.SendMessage(ctx,
+//	    &types.TextBlock{Text: "Why is the layout broken?"},
+//	    screenshot,
+//	)
+func (c *Client) SendMessage(ctx context.Context, content ...types.ContentBlock) (*Turn, error) {
+	blocks, err := protocol.MarshalContentBlocks(content)
+	if err != nil {
+		return nil, err
+	}
+	return c.send(ctx, blocks)
+}
+
+// send writes a user message with the given content to the CLI and queues
+// the Turn that receives its response.
+func (c *Client) send(ctx context.Context, content any) (*Turn, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	if !c.connected {
-		return errors.NewCLIConnectionError("Not connected. Call Connect() first.", nil)
+		return nil, errors.NewCLIConnectionError("Not connected. Call Connect() first.", nil)
 	}
 
-	msg := types.UserInputMessage{
-		Type: "user",
-		Message: types.UserInputInner{
-			Role:    "user",
-			Content: prompt,
-		},
-		SessionID: "default",
+	if c.options.Budget != nil {
+		if err := c.options.Budget.Check(); err != nil {
+			return nil, err
+		}
 	}
+	c.budgetInterrupted.Store(false)
+
+	msg := newUserInput(content)
 
 	data, err := json.Marshal(msg)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return c.transport.Write(ctx, string(data)+"\n")
+	// Queue the turn before writing so that no response can arrive first
+	turn, err := c.router.begin()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.transport.Write(ctx, string(data)+"\n"); err != nil {
+		c.router.abort(turn)
+		return nil, err
+	}
+	return turn, nil
 }
 
 // ReceiveMessages returns a channel that yields all messages from Claude.
 // The channel is closed when the connection ends or an error occurs.
+// Messages that cannot be parsed and API errors are delivered as
+// *types.ErrorMessage; messages of unrecognized types as *types.UnknownMessage.
 //
-// Note: This method should only be called once per connection. For multi-turn
-// conversations, use ReceiveResponse() which stops after each ResultMessage.
+// ReceiveMessages claims every turn in order,
+// skipping turns already claimed through a Turn handle or ReceiveResponse.
+// For multi-turn conversations, prefer the Turn returned by SendQuery or
+// ReceiveResponse, which stop after each ResultMessage.
 //
 // Example:
 //
@@ -252,30 +471,15 @@ func (c *Client) SendQuery(ctx context.Context, prompt string) error {
 //	    }
 //	}
 func (c *Client) ReceiveMessages() <-chan types.Message {
-	msgChan := make(chan types.Message, 100)
-
-	go func() {
-		defer close(msgChan)
-
-		if c.rawMsgChan == nil {
-			return
-		}
-
-		for raw := range c.rawMsgChan {
-			msg, err := protocol.ParseMessage(raw)
-			if err != nil {
-				continue
-			}
-			msgChan <- msg
-		}
-	}()
-
-	return msgChan
+	return c.receive(false)
 }
 
 // ReceiveResponse yields messages until a ResultMessage is received.
-// This is the recommended method for multi-turn conversations as it can be
-// called multiple times, once per query/response cycle.
+// Each call claims the oldest turn not yet claimed, so concurrent calls
+// receive different turns and every message is delivered exactly once.
+// Messages that arrive while no query is pending, such as those of the
+// initial prompt, form their own turn that ends at the next ResultMessage
+// or when a new query is sent.
 //
 // Example:
 //
@@ -291,23 +495,103 @@ func (c *Client) ReceiveMessages() <-chan types.Message {
 //	    // Process second response...
 //	}
 func (c *Client) ReceiveResponse() <-chan types.Message {
-	msgChan := make(chan types.Message, 100)
+	return c.receive(true)
+}
 
-	go func() {
-		defer close(msgChan)
+// ReceiveMessagesSeq returns an iterator over all messages from Claude,
+// like ReceiveMessages. ErrorMessages are yielded as errors. Breaking out
+// of the loop interrupts the running turn and discards its remaining
+// messages; later turns stay available.
+//
+// Example:
+//
+//	for msg, err := range client.ReceiveMessagesSeq() {
+//	    if err != nil {
+//	        log.Printf("Error: %v", err)
+//	        continue
+//	    }
+//	    // Process msg...
+//	}
+func (c *Client) ReceiveMessagesSeq() iter.Seq2[types.Message, error] {
+	return c.receiveSeq(false)
+}
+
+// ReceiveResponseSeq returns an iterator over the messages of the next
+// response, like ReceiveResponse. ErrorMessages are yielded as errors.
+// Breaking out of the loop interrupts the turn and discards its remaining
+// messages.
+//
+// Example:
+//
+//	if _, err := client.SendQuery(ctx, "Hello"); err != nil {
+//	    log.Fatal(err)
+//	}
+//	for msg, err := range client.ReceiveResponseSeq() {
+//	    if err != nil {
+//	        log.Printf("Error: %v", err)
+//	        continue
+//	    }
+//	    // Process msg...
+//	}
+func (c *Client) ReceiveResponseSeq() iter.Seq2[types.Message, error] {
+	return c.receiveSeq(true)
+}
 
-		if c.rawMsgChan == nil {
+// receiveSeq iterates over the messages of the client's turns, claiming them
+// in order as receive does.
+func (c *Client) receiveSeq(untilResult bool) iter.Seq2[types.Message, error] {
+	return func(yield func(types.Message, error) bool) {
+		c.mu.Lock()
+		r := c.router
+		c.mu.Unlock()
+
+		if r == nil {
 			return
 		}
 
-		// Read from the stored channel until we get a ResultMessage
-		for raw := range c.rawMsgChan {
-			msg, err := protocol.ParseMessage(raw)
-			if err != nil {
-				continue
+		for {
+			t := r.claimNext(true)
+			if t == nil || !t.iterate(yield) || untilResult {
+				return
 			}
-			msgChan <- msg
-			if _, isResult := msg.(*types.ResultMessage); isResult {
+		}
+	}
+}
+
+// receive forwards the messages of the client's turns. With untilResult it
+// claims only the oldest unclaimed turn; otherwise it claims every turn in
+// order until the connection ends.
+func (c *Client) receive(untilResult bool) <-chan types.Message {
+	c.mu.Lock()
+	r := c.router
+	c.mu.Unlock()
+
+	if r == nil {
+		msgChan := make(chan types.Message)
+		close(msgChan)
+		return msgChan
+	}
+
+	if untilResult {
+		if t := r.claimNext(false); t != nil {
+			return t.messages()
+		}
+	}
+
+	msgChan := make(chan types.Message, 100)
+
+	go func() {
+		defer close(msgChan)
+
+		for {
+			t := r.claimNext(true)
+			if t == nil {
+				return
+			}
+			for msg := range t.messages() {
+				msgChan <- msg
+			}
+			if untilResult {
 				return
 			}
 		}
@@ -316,6 +600,106 @@ func (c *Client) ReceiveResponse() <-chan types.Message {
 	return msgChan
 }
 
+// route parses raw messages from the query and delivers them to the
+// turn they belong to, until the query's stream ends and the client
+// does not reconnect.
+func (c *Client) route(r *router, q *protocol.Query, done chan struct{}) {
+	var sessionID string
+	var err error
+
+	for {
+		for data := range q.ReceiveMessages() {
+			// Remember the CLI's own session to resume after a crash
+			if t, _ := data["type"].(string); t == "system" || t == "result" {
+				if id, _ := data["session_id"].(string); id != "" {
+					sessionID = id
+				}
+			}
+			c.dispatch(r, data)
+		}
+
+		err = q.Err()
+		next := c.reconnect(q, err, sessionID)
+		if next == nil {
+			break
+		}
+
+		// The running turns died with the old process
+		r.interruptTurns(err)
+		q = next
+	}
+
+	c.mu.Lock()
+	c.err = err
+	c.mu.Unlock()
+
+	r.close(err)
+	close(done)
+}
+
+// dispatch parses a raw message and delivers it to its turn.
+func (c *Client) dispatch(r *router, data map[string]any) {
+	msg, err := protocol.ParseMessage(data)
+	if err != nil {
+		r.deliver(&types.ErrorMessage{Err: err})
+		return
+	}
+	c.observe(msg)
+	r.deliver(msg)
+	if am, ok := msg.(*types.AssistantMessage); ok {
+		if err := protocol.AssistantError(am); err != nil {
+			r.deliver(&types.ErrorMessage{Err: err})
+		}
+	}
+}
+
+// observe charges the shared budget for msg and interrupts the running turn
+// once the budget is exhausted. Further queries are refused by SendQuery.
+func (c *Client) observe(msg types.Message) {
+	if c.meter == nil {
+		return
+	}
+	if err := c.meter.Observe(msg); err == nil {
+		return
+	}
+	if _, done := msg.(*types.ResultMessage); done {
+		return
+	}
+	if c.budgetInterrupted.CompareAndSwap(false, true) {
+		go func() { _ = c.Interrupt(context.Background()) }()
+	}
+}
+
+// Done returns a channel that is closed when the connection ends, whether
+// the CLI exited, failed or Close was called. It returns nil before Connect.
+//
+// Example:
+//
+//	<-client.Done()
+//	if err := client.Err(); err != nil {
+//	    log.Printf("Connection failed: %v", err)
+//	}
+func (c *Client) Done() <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.done
+}
+
+// Err returns the error that ended the connection, such as a
+// *errors.ProcessError carrying the CLI's exit code and the tail of its
+// stderr. It returns nil while the connection is open or after it ended
+// cleanly. An error that stopped the ConnectStream input is reported even
+// while the connection stays open.
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return c.err
+	}
+	return c.inputErr
+}
+
 // Interrupt sends an interrupt signal to stop the current operation.
 //
 // Example:
@@ -386,23 +770,70 @@ func (c *Client) GetServerInfo() map[string]any {
 // Close disconnects from Claude and cleans up resources.
 func (c *Client) Close() error {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	c.connected = false
 
-	if c.query != nil {
-		_ = c.query.Close()
-		c.query = nil
+	if c.stop != nil {
+		close(c.stop)
+		c.stop = nil
+	}
+
+	if c.cancelInput != nil {
+		c.cancelInput()
+		c.cancelInput = nil
 	}
 
-	if c.transport != nil {
-		_ = c.transport.Close()
-		c.transport = nil
+	sessions := c.sessions
+	c.sessions = nil
+	q, trans, release := c.query, c.transport, c.release
+	c.query, c.transport, c.release = nil, nil, nil
+	c.mu.Unlock()
+
+	// Closing waits for processes and background hook work, which may
+	// call back into the client, so c.mu is not held. Sessions also take
+	// c.mu while starting.
+	for _, s := range sessions {
+		_ = s.close()
+	}
+	if q != nil {
+		_ = q.Close()
+	}
+	if trans != nil {
+		_ = trans.Close()
+	}
+	if release != nil {
+		release()
 	}
 
 	return nil
 }
 
+// startClient connects a Client whose process lives until the Client is
+// closed or lifetime ends. ctx only bounds the startup: cancelling it
+// afterwards leaves the process running.
+func startClient(lifetime, ctx context.Context, options *AgentOptions) (*Client, error) {
+	client, err := NewClient(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	procCtx, cancel := context.WithCancel(lifetime)
+	stop := context.AfterFunc(ctx, cancel)
+	err = client.Connect(procCtx, "")
+	if !stop() && err == nil {
+		err = ctx.Err()
+	}
+
+	client.mu.Lock()
+	client.release = cancel
+	client.mu.Unlock()
+
+	if err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
 // Helper functions for creating pointers to primitive types
 
 // String returns a pointer to the given string.
@@ -0,0 +1,220 @@
+package cost
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/nabkey/claude-agent-sdk-go/errors"
+	"github.com/nabkey/claude-agent-sdk-go/types"
+)
+
+// Budget is a spending limit shared by any number of queries and clients.
+// Attach it to AgentOptions.Budget: queries are refused once it is
+// exhausted, running turns are interrupted when they cross it, and each CLI
+// process is started with --max-budget-usd capped at the remaining amount.
+// It is safe for concurrent use.
+//
+// Example:
+//
+//	team := cost.NewDailyBudget("search-team", 50, time.Local)
+//
+//	options := &claude.AgentOptions{Budget: team}
+//	_, err := claude.QueryText(ctx, "Summarize the incident report", options)
+//
+//	var exceeded *errors.BudgetExceededError
+//	if errors.As(err, &exceeded) {
+//	    log.Printf("daily budget reached: $%.2f", exceeded.SpentUSD)
+//	}
+type Budget struct {
+	// Name identifies the budget in BudgetExceededError.
+	Name string
+
+	// Estimate prices streaming usage before the CLI reports the actual
+	// cost in a ResultMessage, so a turn can be interrupted while it runs.
+	// It is only consulted when IncludePartialMessages is enabled; when nil,
+	// spend is tracked from ResultMessages alone.
+	Estimate func(model string, usage *types.Usage) float64
+
+	mu       sync.Mutex
+	limitUSD float64
+	spentUSD float64
+	location *time.Location
+	resetAt  time.Time
+	now      func() time.Time
+}
+
+// NewBudget creates a budget of limitUSD that never resets, suitable for a
+// single job.
+func NewBudget(name string, limitUSD float64) *Budget {
+	return &Budget{Name: name, limitUSD: limitUSD, now: time.Now}
+}
+
+// NewDailyBudget creates a budget of limitUSD that resets at midnight in
+// loc. A nil loc means UTC.
+func NewDailyBudget(name string, limitUSD float64, loc *time.Location) *Budget {
+	if loc == nil {
+		loc = time.UTC
+	}
+	b := &Budget{Name: name, limitUSD: limitUSD, location: loc, now: time.Now}
+	b.resetAt = nextMidnight(b.now(), loc)
+	return b
+}
+
+// Limit returns the budget's limit per period in USD.
+func (b *Budget) Limit() float64 {
+	return b.limitUSD
+}
+
+// Spent returns the amount spent in the current period.
+func (b *Budget) Spent() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.roll()
+	return b.spentUSD
+}
+
+// Remaining returns the amount left in the current period, never negative.
+func (b *Budget) Remaining() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.roll()
+	return math.Max(0, b.limitUSD-b.spentUSD)
+}
+
+// Check returns a *errors.BudgetExceededError if the budget is exhausted.
+func (b *Budget) Check() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.roll()
+	return b.exceeded()
+}
+
+// Charge adds usd to the current period's spend; negative amounts refund.
+// It returns a *errors.BudgetExceededError if the budget is exhausted
+// afterwards.
+func (b *Budget) Charge(usd float64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.roll()
+	b.spentUSD = math.Max(0, b.spentUSD+usd)
+	return b.exceeded()
+}
+
+// Meter returns a Meter that charges the budget for the messages of one
+// CLI process.
+func (b *Budget) Meter() *Meter {
+	return &Meter{budget: b}
+}
+
+// exceeded reports the exhausted budget as an error. Callers hold b.mu.
+func (b *Budget) exceeded() error {
+	if b.spentUSD < b.limitUSD {
+		return nil
+	}
+	return errors.NewBudgetExceededError(b.Name, b.limitUSD, b.spentUSD)
+}
+
+// roll starts a new period for daily budgets. Callers hold b.mu.
+func (b *Budget) roll() {
+	if b.location == nil {
+		return
+	}
+	if now := b.now(); !now.Before(b.resetAt) {
+		b.spentUSD = 0
+		b.resetAt = nextMidnight(now, b.location)
+	}
+}
+
+// nextMidnight returns the first midnight in loc after t.
+func nextMidnight(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+}
+
+// Meter charges a Budget for the messages of a single CLI process as they
+// arrive. Streaming usage is charged at the budget's Estimate, and the
+// estimate is settled against the actual cost when the ResultMessage for
+// the turn arrives.
+type Meter struct {
+	budget *Budget
+
+	mu          sync.Mutex
+	lastCostUSD float64
+	pendingUSD  float64
+	model       string
+	usage       types.Usage
+	estimateUSD float64
+}
+
+// Observe charges the budget for msg and returns a
+// *errors.BudgetExceededError if the budget is exhausted.
+func (m *Meter) Observe(msg types.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch v := msg.(type) {
+	case *types.StreamEvent:
+		if m.budget.Estimate == nil || !m.updateUsage(v.Event) {
+			return m.budget.Check()
+		}
+		estimate := m.budget.Estimate(m.model, &m.usage)
+		delta := estimate - m.estimateUSD
+		m.estimateUSD = estimate
+		m.pendingUSD += delta
+		return m.budget.Charge(delta)
+
+	case *types.ResultMessage:
+		// TotalCostUSD is cumulative for the process
+		var actual float64
+		if v.TotalCostUSD != nil && *v.TotalCostUSD > m.lastCostUSD {
+			actual = *v.TotalCostUSD - m.lastCostUSD
+			m.lastCostUSD = *v.TotalCostUSD
+		}
+		delta := actual - m.pendingUSD
+		m.pendingUSD = 0
+		m.estimateUSD = 0
+		m.usage = types.Usage{}
+		return m.budget.Charge(delta)
+	}
+
+	return m.budget.Check()
+}
+
+// updateUsage tracks the usage of the message being streamed and reports
+// whether it changed.
+func (m *Meter) updateUsage(event map[string]any) bool {
+	switch event["type"] {
+	case "message_start":
+		message, _ := event["message"].(map[string]any)
+		m.model, _ = message["model"].(string)
+		// Earlier messages of the turn stay charged as pending
+		m.estimateUSD = 0
+		m.usage = types.Usage{}
+		usage, _ := message["usage"].(map[string]any)
+		applyUsage(&m.usage, usage)
+		return true
+	case "message_delta":
+		usage, ok := event["usage"].(map[string]any)
+		if !ok {
+			return false
+		}
+		applyUsage(&m.usage, usage)
+		return true
+	}
+	return false
+}
+
+// applyUsage overwrites the token counts present in a streamed usage
+// object; counts in message_delta events are cumulative for the message.
+func applyUsage(usage *types.Usage, data map[string]any) {
+	set := func(dst *int, key string) {
+		if v, ok := data[key].(float64); ok {
+			*dst = int(v)
+		}
+	}
+	set(&usage.InputTokens, "input_tokens")
+	set(&usage.OutputTokens, "output_tokens")
+	set(&usage.CacheCreationInputTokens, "cache_creation_input_tokens")
+	set(&usage.CacheReadInputTokens, "cache_read_input_tokens")
+}
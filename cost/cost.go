@@ -0,0 +1,263 @@
+// Package cost aggregates spend and token usage reported in ResultMessages
+// across many queries, clients and sessions.
+//
+// The CLI reports TotalCostUSD and ModelUsage cumulatively for the lifetime
+// of its process. A one-shot Query runs one process per call, so its results
+// can be passed to Tracker.Record directly. A Client keeps one process for
+// all of its turns; feed its results through a Recorder so that each turn is
+// counted once.
+//
+// Example:
+//
+//	tracker := cost.NewTracker()
+//	recorder := tracker.Recorder("team:search", "job:reindex")
+//
+//	for msg := range client.ReceiveResponse() {
+//	    if result, ok := msg.(*types.ResultMessage); ok {
+//	        recorder.Record(result)
+//	    }
+//	}
+//
+//	fmt.Printf("job spend: $%.4f\n", tracker.Tag("job:reindex").CostUSD)
+package cost
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/nabkey/claude-agent-sdk-go/types"
+)
+
+// Totals is accumulated spend and usage.
+type Totals struct {
+	// CostUSD is the total cost in US dollars.
+	CostUSD float64
+	// Usage is the total token usage.
+	Usage types.Usage
+	// ModelUsage breaks usage and cost down by model.
+	ModelUsage map[string]types.ModelUsage
+	// Results is the number of results recorded.
+	Results int
+	// Errors is the number of recorded results that were errors.
+	Errors int
+	// DurationAPIMS is the total time spent in API calls.
+	DurationAPIMS int
+}
+
+// add accumulates one turn into t.
+func (t *Totals) add(turn *Totals) {
+	t.CostUSD += turn.CostUSD
+	t.Usage.Add(&turn.Usage)
+	for model, usage := range turn.ModelUsage {
+		if t.ModelUsage == nil {
+			t.ModelUsage = make(map[string]types.ModelUsage)
+		}
+		m := t.ModelUsage[model]
+		m.Add(usage)
+		t.ModelUsage[model] = m
+	}
+	t.Results += turn.Results
+	t.Errors += turn.Errors
+	t.DurationAPIMS += turn.DurationAPIMS
+}
+
+// clone returns a deep copy of t.
+func (t *Totals) clone() Totals {
+	c := *t
+	c.Usage.Raw = nil
+	if t.Usage.ServerToolUse != nil {
+		stu := *t.Usage.ServerToolUse
+		c.Usage.ServerToolUse = &stu
+	}
+	if t.ModelUsage != nil {
+		c.ModelUsage = make(map[string]types.ModelUsage, len(t.ModelUsage))
+		for model, usage := range t.ModelUsage {
+			c.ModelUsage[model] = usage
+		}
+	}
+	return c
+}
+
+// Tracker accumulates Totals overall, per session and per tag. It is safe
+// for concurrent use.
+type Tracker struct {
+	mu       sync.Mutex
+	total    Totals
+	sessions map[string]*Totals
+	tags     map[string]*Totals
+}
+
+// NewTracker creates an empty Tracker.
+func NewTracker() *Tracker {
+	return &Tracker{
+		sessions: make(map[string]*Totals),
+		tags:     make(map[string]*Totals),
+	}
+}
+
+// Record adds a result produced by its own CLI process, such as the result
+// of a one-shot Query, and attributes it to the given tags.
+func (t *Tracker) Record(result *types.ResultMessage, tags ...string) {
+	if result == nil {
+		return
+	}
+	t.add(result.SessionID, turnOf(result, nil, 0), tags)
+}
+
+// Recorder returns a Recorder for the results of one CLI process, such as
+// all turns of a Client. Every result it records is attributed to tags.
+func (t *Tracker) Recorder(tags ...string) *Recorder {
+	return &Recorder{tracker: t, tags: tags}
+}
+
+// Total returns the totals across everything recorded.
+func (t *Tracker) Total() Totals {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.total.clone()
+}
+
+// Session returns the totals recorded for a session.
+func (t *Tracker) Session(sessionID string) Totals {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if s, ok := t.sessions[sessionID]; ok {
+		return s.clone()
+	}
+	return Totals{}
+}
+
+// Tag returns the totals recorded under a tag.
+func (t *Tracker) Tag(tag string) Totals {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if s, ok := t.tags[tag]; ok {
+		return s.clone()
+	}
+	return Totals{}
+}
+
+// Sessions returns the IDs of all recorded sessions in sorted order.
+func (t *Tracker) Sessions() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return sortedKeys(t.sessions)
+}
+
+// Tags returns all tags with recorded spend in sorted order.
+func (t *Tracker) Tags() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return sortedKeys(t.tags)
+}
+
+// Reset discards everything recorded.
+func (t *Tracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.total = Totals{}
+	t.sessions = make(map[string]*Totals)
+	t.tags = make(map[string]*Totals)
+}
+
+// add accumulates a turn into the overall, session and tag totals.
+func (t *Tracker) add(sessionID string, turn *Totals, tags []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.total.add(turn)
+	if sessionID != "" {
+		s, ok := t.sessions[sessionID]
+		if !ok {
+			s = &Totals{}
+			t.sessions[sessionID] = s
+		}
+		s.add(turn)
+	}
+	for _, tag := range tags {
+		s, ok := t.tags[tag]
+		if !ok {
+			s = &Totals{}
+			t.tags[tag] = s
+		}
+		s.add(turn)
+	}
+}
+
+// Recorder records the results of a single CLI process into a Tracker,
+// converting the process's cumulative cost and per-model usage into
+// per-turn amounts. It is safe for concurrent use.
+type Recorder struct {
+	tracker *Tracker
+	tags    []string
+
+	mu         sync.Mutex
+	lastCost   float64
+	lastModels map[string]types.ModelUsage
+}
+
+// Record adds a result from the recorder's process.
+func (r *Recorder) Record(result *types.ResultMessage) {
+	if result == nil {
+		return
+	}
+
+	r.mu.Lock()
+	turn := turnOf(result, r.lastModels, r.lastCost)
+	if result.TotalCostUSD != nil {
+		r.lastCost = *result.TotalCostUSD
+	}
+	if result.ModelUsage != nil {
+		r.lastModels = result.ModelUsage
+	}
+	r.mu.Unlock()
+
+	r.tracker.add(result.SessionID, turn, r.tags)
+}
+
+// Reset forgets the cumulative amounts seen so far, so that the next result
+// is recorded as coming from a new CLI process. Call it when the process
+// is replaced, such as after a Client reconnects, once the last result of
+// the previous process has been recorded.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastCost = 0
+	r.lastModels = nil
+}
+
+// turnOf builds the totals for one result, subtracting the cumulative
+// amounts already seen from the same process.
+func turnOf(result *types.ResultMessage, prevModels map[string]types.ModelUsage, prevCost float64) *Totals {
+	turn := &Totals{
+		Results:       1,
+		DurationAPIMS: result.DurationAPIMS,
+	}
+	if result.IsError {
+		turn.Errors = 1
+	}
+	if result.TotalCostUSD != nil && *result.TotalCostUSD > prevCost {
+		turn.CostUSD = *result.TotalCostUSD - prevCost
+	}
+	if result.Usage != nil {
+		turn.Usage.Add(result.Usage)
+	}
+	for model, usage := range result.ModelUsage {
+		if turn.ModelUsage == nil {
+			turn.ModelUsage = make(map[string]types.ModelUsage)
+		}
+		turn.ModelUsage[model] = usage.Sub(prevModels[model])
+	}
+	return turn
+}
+
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys(m map[string]*Totals) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -4,6 +4,7 @@ package errors
 import (
 	"errors"
 	"fmt"
+	"time"
 )
 
 // ClaudeSDKError is the base error type for all Claude SDK errors.
@@ -153,6 +154,110 @@ func NewTimeoutError(operation string) *TimeoutError {
 	}
 }
 
+// BudgetExceededError is raised when a shared spending budget is exhausted.
+type BudgetExceededError struct {
+	ClaudeSDKError
+	// Budget is the name of the exhausted budget, if any.
+	Budget string
+	// LimitUSD is the budget's limit for the current period.
+	LimitUSD float64
+	// SpentUSD is the amount spent in the current period.
+	SpentUSD float64
+}
+
+// NewBudgetExceededError creates a new BudgetExceededError.
+func NewBudgetExceededError(budget string, limitUSD, spentUSD float64) *BudgetExceededError {
+	message := fmt.Sprintf("Budget exceeded: spent $%.4f of $%.4f", spentUSD, limitUSD)
+	if budget != "" {
+		message = fmt.Sprintf("Budget %q exceeded: spent $%.4f of $%.4f", budget, spentUSD, limitUSD)
+	}
+	return &BudgetExceededError{
+		ClaudeSDKError: ClaudeSDKError{
+			Message: message,
+		},
+		Budget:   budget,
+		LimitUSD: limitUSD,
+		SpentUSD: spentUSD,
+	}
+}
+
+// APIError is raised when the Claude API rejects a request made on behalf
+// of an assistant turn. More specific errors embed it: AuthenticationError,
+// BillingError, RateLimitError and APIServerError. Use AsAPIError to match
+// any of them.
+type APIError struct {
+	ClaudeSDKError
+	// Type is the error type reported by the CLI, such as "rate_limit".
+	Type string
+	// RetryAfter is the suggested delay before retrying, or zero if unknown.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) apiError() *APIError { return e }
+
+// Retryable reports whether retrying the request may succeed.
+func (e *APIError) Retryable() bool {
+	return e.Type == "rate_limit" || e.Type == "server_error" || e.Type == "overloaded"
+}
+
+// AuthenticationError is raised when the API key or credentials are invalid.
+type AuthenticationError struct {
+	APIError
+}
+
+// BillingError is raised when the account cannot be billed for a request.
+type BillingError struct {
+	APIError
+}
+
+// RateLimitError is raised when a request is rate limited.
+type RateLimitError struct {
+	APIError
+}
+
+// APIServerError is raised when the API fails with a server-side error.
+type APIServerError struct {
+	APIError
+}
+
+// NewAPIError creates the error matching errorType: an AuthenticationError,
+// BillingError, RateLimitError or APIServerError, or a plain APIError for
+// other types.
+func NewAPIError(errorType, message string, retryAfter time.Duration) error {
+	if message == "" {
+		message = fmt.Sprintf("API error: %s", errorType)
+	}
+	base := APIError{
+		ClaudeSDKError: ClaudeSDKError{
+			Message: message,
+		},
+		Type:       errorType,
+		RetryAfter: retryAfter,
+	}
+
+	switch errorType {
+	case "authentication_failed":
+		return &AuthenticationError{APIError: base}
+	case "billing_error":
+		return &BillingError{APIError: base}
+	case "rate_limit":
+		return &RateLimitError{APIError: base}
+	case "server_error":
+		return &APIServerError{APIError: base}
+	default:
+		return &base
+	}
+}
+
+// AsAPIError finds the first API error of any kind in err's chain.
+func AsAPIError(err error) (*APIError, bool) {
+	var target interface{ apiError() *APIError }
+	if errors.As(err, &target) {
+		return target.apiError(), true
+	}
+	return nil, false
+}
+
 // Helper functions for error type checking using errors.As
 
 // Is checks if the target error is of the specified type.
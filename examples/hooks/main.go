@@ -104,7 +104,7 @@ func main() {
 	prompt := "Please run 'echo Hello World' using bash."
 	fmt.Printf("User: %s\n\n", prompt)
 
-	if err := client.SendQuery(ctx, prompt); err != nil {
+	if _, err := client.SendQuery(ctx, prompt); err != nil {
 		log.Fatal(err)
 	}
 
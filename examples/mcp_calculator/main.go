@@ -122,7 +122,7 @@ func main() {
 
 	// Ask Claude to use the calculator
 	prompt := "Please calculate (5 + 3) × 2 using the calculator tools, then find the square root of 64."
-	if err := client.SendQuery(ctx, prompt); err != nil {
+	if _, err := client.SendQuery(ctx, prompt); err != nil {
 		log.Fatal(err)
 	}
 
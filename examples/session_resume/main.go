@@ -58,7 +58,7 @@ func createInitialSession(ctx context.Context) string {
 	prompt := "Remember this number: 42. It's the answer to everything."
 	fmt.Printf("User: %s\n\n", prompt)
 
-	if err := client.SendQuery(ctx, prompt); err != nil {
+	if _, err := client.SendQuery(ctx, prompt); err != nil {
 		log.Fatal(err)
 	}
 
@@ -106,7 +106,7 @@ func resumeSession(ctx context.Context, sessionID string) {
 	prompt := "What number did I ask you to remember?"
 	fmt.Printf("User: %s\n\n", prompt)
 
-	if err := client.SendQuery(ctx, prompt); err != nil {
+	if _, err := client.SendQuery(ctx, prompt); err != nil {
 		log.Fatal(err)
 	}
 
@@ -150,7 +150,7 @@ func continueConversation(ctx context.Context) {
 	prompt := "Can you remind me what we were talking about?"
 	fmt.Printf("User: %s\n\n", prompt)
 
-	if err := client.SendQuery(ctx, prompt); err != nil {
+	if _, err := client.SendQuery(ctx, prompt); err != nil {
 		log.Fatal(err)
 	}
 
@@ -36,7 +36,7 @@ func main() {
 
 	// First query
 	fmt.Println("User: What is the capital of France?")
-	if err := client.SendQuery(ctx, "What is the capital of France?"); err != nil {
+	if _, err := client.SendQuery(ctx, "What is the capital of France?"); err != nil {
 		log.Fatal(err)
 	}
 
@@ -47,7 +47,7 @@ func main() {
 
 	// Follow-up query
 	fmt.Println("\nUser: What's a famous landmark there?")
-	if err := client.SendQuery(ctx, "What's a famous landmark there?"); err != nil {
+	if _, err := client.SendQuery(ctx, "What's a famous landmark there?"); err != nil {
 		log.Fatal(err)
 	}
 
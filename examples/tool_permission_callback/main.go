@@ -58,7 +58,7 @@ func main() {
 	fmt.Println("Sending query to Claude...")
 	fmt.Printf("User: %s\n\n", prompt)
 
-	if err := client.SendQuery(ctx, prompt); err != nil {
+	if _, err := client.SendQuery(ctx, prompt); err != nil {
 		log.Fatal(err)
 	}
 
@@ -0,0 +1,162 @@
+package guard
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/nabkey/claude-agent-sdk-go/shell"
+	"github.com/nabkey/claude-agent-sdk-go/types"
+)
+
+// BashPolicy is a permission rule set for Bash tool calls. Commands are
+// parsed with the shell package, so every program in a pipeline, command
+// list, substitution or wrapper (sudo, env, command, `bash -c`, ...) is
+// checked. Writes to relative paths are rejected after a command that
+// changes directory, such as cd, since they can no longer be resolved.
+//
+// The analysis is static and best-effort. Commands whose programs cannot be
+// known without running them, such as `$(echo rm) -rf /` or `curl URL | sh`,
+// are rejected, but programs that run code of their own, such as script
+// files, `python -c` or make, are checked by name only. Deny is not a
+// sandbox; combine it with Allow for untrusted input.
+//
+// Example:
+//
+//	repo, _ := guard.NewPathGuard("/srv/repo")
+//	policy := &guard.BashPolicy{
+//	    Allow:  []string{"cd", "go test", "git status"},
+//	    Writes: repo,
+//	}
+//
+//	options := &claude.AgentOptions{
+//	    CanUseTool: policy.CanUseTool,
+//	}
+type BashPolicy struct {
+	// Allow lists permitted command prefixes such as "go test" or "git status".
+	// A command matches when its program (by base name) and leading arguments
+	// equal the prefix words. When empty, every command not denied is allowed.
+	Allow []string
+
+	// Deny lists command prefixes that are always rejected, even if allowed.
+	Deny []string
+
+	// Writes restricts the files commands may write to the guard's roots.
+	// Nil leaves writes unrestricted.
+	Writes *PathGuard
+}
+
+// Check parses a shell command and returns an error describing the first
+// rule it violates, or nil if the command is permitted. Commands that cannot
+// be parsed are rejected.
+func (p *BashPolicy) Check(command string) error {
+	script, err := shell.Parse(command)
+	if err != nil {
+		return fmt.Errorf("cannot analyze command: %w", err)
+	}
+
+	for _, cmd := range script.Commands {
+		if cmd.Name == "" {
+			continue
+		}
+		if cmd.Opaque() {
+			return fmt.Errorf("command %q runs commands that cannot be determined before it runs", strings.Join(cmd.Words(), " "))
+		}
+		if rule, ok := matchPrefix(cmd, p.Deny); ok {
+			return fmt.Errorf("command %q is denied by rule %q", strings.Join(cmd.Words(), " "), rule)
+		}
+		if len(p.Allow) > 0 {
+			if _, ok := matchPrefix(cmd, p.Allow); !ok {
+				return fmt.Errorf("command %q is not in the allowed list", strings.Join(cmd.Words(), " "))
+			}
+		}
+	}
+
+	if p.Writes != nil {
+		// Relative targets are resolved against the guard's working
+		// directory, which no longer applies once a command changes it
+		moved := false
+		for _, cmd := range script.Commands {
+			for _, file := range cmd.WrittenFiles() {
+				if file == "" || devicePaths[file] {
+					continue
+				}
+				if moved && !filepath.IsAbs(file) && !strings.HasPrefix(file, "~") {
+					return fmt.Errorf("command writes to relative path %s after changing directory", file)
+				}
+				if !p.Writes.Allowed(file) {
+					return fmt.Errorf("command writes to %s, which is outside the allowed directories", file)
+				}
+			}
+			if cmd.ChangesDirectory() {
+				moved = true
+			}
+		}
+	}
+
+	return nil
+}
+
+// CanUseTool implements a CanUseTool callback that applies the policy to
+// Bash tool calls and allows all other tools.
+func (p *BashPolicy) CanUseTool(
+	ctx context.Context,
+	toolName string,
+	input map[string]any,
+	permCtx types.ToolPermissionContext,
+) (types.PermissionResult, error) {
+	if toolName != "Bash" {
+		return &types.PermissionResultAllow{}, nil
+	}
+
+	command, _ := input["command"].(string)
+	if err := p.Check(command); err != nil {
+		return &types.PermissionResultDeny{Message: err.Error()}, nil
+	}
+	return &types.PermissionResultAllow{}, nil
+}
+
+// PreToolUseHook returns a hook callback that denies Bash calls violating
+// the policy. Other calls are left to the CLI's regular permission flow.
+func (p *BashPolicy) PreToolUseHook() types.HookCallback {
+	return func(ctx context.Context, input types.HookInput, toolUseID *string, hookCtx *types.HookContext) (*types.HookOutput, error) {
+		pre, ok := input.(*types.PreToolUseHookInput)
+		if !ok || pre.ToolName != "Bash" {
+			return nil, nil
+		}
+
+		command, _ := pre.ToolInput["command"].(string)
+		if err := p.Check(command); err != nil {
+			deny := "deny"
+			reason := err.Error()
+			return &types.HookOutput{
+				HookSpecificOutput: &types.PreToolUseHookSpecificOutput{
+					HookEventName:            "PreToolUse",
+					PermissionDecision:       &deny,
+					PermissionDecisionReason: &reason,
+				},
+			}, nil
+		}
+		return nil, nil
+	}
+}
+
+// HookMatcher returns a PreToolUse HookMatcher that applies the policy to Bash.
+func (p *BashPolicy) HookMatcher() types.HookMatcher {
+	matcher := "Bash"
+	return types.HookMatcher{
+		Matcher: &matcher,
+		Hooks:   []types.HookCallback{p.PreToolUseHook()},
+	}
+}
+
+// matchPrefix returns the first rule whose words prefix the command.
+func matchPrefix(cmd *shell.Command, rules []string) (string, bool) {
+	for _, rule := range rules {
+		if cmd.HasPrefix(strings.Fields(rule)...) {
+			return rule, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,96 @@
+package guard
+
+import (
+	"testing"
+)
+
+func TestBashPolicyDeny(t *testing.T) {
+	policy := &BashPolicy{Deny: []string{"rm"}}
+
+	tests := []struct {
+		command string
+		allowed bool
+	}{
+		{"ls -la", true},
+		{"rm -rf /", false},
+		{"command rm -rf /", false},
+		{"command -p rm -rf /", false},
+		{"command -v rm", true},
+		{"bash -c 'rm -rf /'", false},
+		{"bash -lc 'rm -rf /'", false},
+		{"sh -ec 'rm -rf /'", false},
+		{"bash -o pipefail -c 'rm -rf /'", false},
+		{"bash -c -- 'rm -rf /'", false},
+		{"sudo -u root bash -xc 'rm -rf /'", false},
+		{"env FOO=1 command bash -lc 'echo ok; rm -rf /'", false},
+		{"bash -l script.sh", true},
+		{"$(echo rm) -rf /", false},
+		{"`echo rm` -rf /", false},
+		{"$RM -rf /", false},
+		{"/bin/r? -rf /", false},
+		{"{rm,-rf,/}", false},
+		{"echo rm -rf / | bash", false},
+		{"bash <<< 'rm -rf /'", false},
+		{"bash -s < script.sh", false},
+		{"sh -c \"$(echo rm) /\"", false},
+		{"busybox rm x", false},
+		{"case x in x) rm y;; esac", false},
+		{"case x in x) ls;; esac", true},
+		{"echo $HOME | cat", true},
+	}
+
+	for _, tt := range tests {
+		err := policy.Check(tt.command)
+		if allowed := err == nil; allowed != tt.allowed {
+			t.Errorf("Check(%q) = %v, want allowed %v", tt.command, err, tt.allowed)
+		}
+	}
+}
+
+func TestBashPolicyWrites(t *testing.T) {
+	repo, err := NewPathGuard(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := &BashPolicy{Writes: repo}
+	allowList := &BashPolicy{Allow: []string{"cd", "go test"}, Writes: repo}
+
+	tests := []struct {
+		policy  *BashPolicy
+		command string
+		allowed bool
+	}{
+		{policy, "echo x > out.txt", true},
+		{policy, "touch sub/file", true},
+		{policy, "echo x > /tmp/evil", false},
+		{policy, "echo x > ../evil", false},
+		{policy, "go test ./... > /dev/null", true},
+		{policy, "cd / && touch etc/evil", false},
+		{policy, "cd /tmp; echo x > evil", false},
+		{policy, "pushd / && touch etc/evil", false},
+		{policy, "(cd / && rm etc/passwd)", false},
+		{policy, "env -C / touch etc/evil", false},
+		{policy, "bash -lc 'cd / && touch etc/evil'", false},
+		{policy, "echo x > out.txt && cd sub", true},
+		{policy, "cd sub && echo x > /dev/null", true},
+		{allowList, "cd sub && go test ./...", true},
+		{allowList, "cd / && go test ./... > etc/cron.d/x", false},
+		{allowList, "rm -rf /", false},
+		{policy, "ln -s /etc/passwd", true},
+		{policy, "ln -s /etc/passwd /tmp/evil", false},
+		{policy, "chmod 777 /etc/passwd", false},
+		{policy, "chmod +x run.sh", true},
+		{policy, "tar -xzf archive.tgz -C /", false},
+		{policy, "tar xzf archive.tgz", true},
+		{policy, "tar czf /tmp/out.tgz .", false},
+		{policy, "git -C / init", false},
+		{policy, "git status", true},
+	}
+
+	for _, tt := range tests {
+		err := tt.policy.Check(tt.command)
+		if allowed := err == nil; allowed != tt.allowed {
+			t.Errorf("Check(%q) = %v, want allowed %v", tt.command, err, tt.allowed)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+// Package guard provides built-in permission checks that can be attached to
+// AgentOptions as CanUseTool callbacks or hooks.
+package guard
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nabkey/claude-agent-sdk-go/shell"
+	"github.com/nabkey/claude-agent-sdk-go/types"
+)
+
+// FileTools is the matcher pattern covering every tool inspected by PathGuard.
+const FileTools = "Read|Write|Edit|MultiEdit|Glob|Grep|NotebookEdit|Bash"
+
+// devicePaths are always allowed in Bash commands, typically as redirection targets.
+var devicePaths = map[string]bool{
+	"/dev/null":   true,
+	"/dev/stdin":  true,
+	"/dev/stdout": true,
+	"/dev/stderr": true,
+	"/dev/tty":    true,
+}
+
+// pathFields lists the input fields that carry file system paths for each tool.
+var pathFields = map[string][]string{
+	"Read":         {"file_path"},
+	"Write":        {"file_path"},
+	"Edit":         {"file_path"},
+	"MultiEdit":    {"file_path"},
+	"NotebookEdit": {"notebook_path"},
+	"Glob":         {"path", "pattern"},
+	"Grep":         {"path"},
+}
+
+// PathGuard restricts file tools to the working directory and any
+// additional directories. Paths are made absolute, cleaned and resolved
+// through symlinks before being compared, so neither `..` segments nor
+// links pointing elsewhere can escape the allowed roots.
+type PathGuard struct {
+	cwd   string
+	roots []string
+}
+
+// NewPathGuard creates a PathGuard that allows access below cwd and addDirs.
+// An empty cwd defaults to the current process working directory.
+//
+// Example:
+//
+//	pg, err := guard.NewPathGuard("/srv/repo", "/tmp/scratch")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//
+//	options := &claude.AgentOptions{
+//	    Cwd:        claude.String("/srv/repo"),
+//	    AddDirs:    []string{"/tmp/scratch"},
+//	    CanUseTool: pg.CanUseTool,
+//	}
+func NewPathGuard(cwd string, addDirs ...string) (*PathGuard, error) {
+	if cwd == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("resolve working directory: %w", err)
+		}
+		cwd = wd
+	}
+
+	absCwd, err := filepath.Abs(cwd)
+	if err != nil {
+		return nil, fmt.Errorf("resolve working directory: %w", err)
+	}
+
+	g := &PathGuard{cwd: absCwd}
+	for _, dir := range append([]string{absCwd}, addDirs...) {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(absCwd, dir)
+		}
+		g.roots = append(g.roots, resolvePath(dir))
+	}
+
+	return g, nil
+}
+
+// Roots returns the resolved directories the guard allows access to.
+func (g *PathGuard) Roots() []string {
+	return append([]string{}, g.roots...)
+}
+
+// Allowed reports whether path resolves to a location inside one of the
+// allowed roots. Relative paths are interpreted against the working directory.
+func (g *PathGuard) Allowed(path string) bool {
+	resolved := g.resolve(path)
+	for _, root := range g.roots {
+		prefix := root
+		if !strings.HasSuffix(prefix, string(filepath.Separator)) {
+			prefix += string(filepath.Separator)
+		}
+		if resolved == root || strings.HasPrefix(resolved, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check inspects a tool invocation and returns an error describing the first
+// path that falls outside the allowed roots, or nil if the call is permitted.
+func (g *PathGuard) Check(toolName string, input map[string]any) error {
+	paths, err := g.paths(toolName, input)
+	if err != nil {
+		return fmt.Errorf("%s: cannot analyze command: %w", toolName, err)
+	}
+	for _, path := range paths {
+		if !g.Allowed(path) {
+			return fmt.Errorf("%s: path %s is outside the allowed directories", toolName, path)
+		}
+	}
+	return nil
+}
+
+// CanUseTool implements a CanUseTool callback that denies tool calls
+// touching paths outside the allowed roots and allows everything else.
+func (g *PathGuard) CanUseTool(
+	ctx context.Context,
+	toolName string,
+	input map[string]any,
+	permCtx types.ToolPermissionContext,
+) (types.PermissionResult, error) {
+	if err := g.Check(toolName, input); err != nil {
+		return &types.PermissionResultDeny{Message: err.Error()}, nil
+	}
+	return &types.PermissionResultAllow{}, nil
+}
+
+// PreToolUseHook returns a hook callback that denies tool calls touching
+// paths outside the allowed roots. Other calls are left to the CLI's
+// regular permission flow.
+func (g *PathGuard) PreToolUseHook() types.HookCallback {
+	return func(ctx context.Context, input types.HookInput, toolUseID *string, hookCtx *types.HookContext) (*types.HookOutput, error) {
+		pre, ok := input.(*types.PreToolUseHookInput)
+		if !ok {
+			return nil, nil
+		}
+
+		if err := g.Check(pre.ToolName, pre.ToolInput); err != nil {
+			deny := "deny"
+			reason := err.Error()
+			return &types.HookOutput{
+				HookSpecificOutput: &types.PreToolUseHookSpecificOutput{
+					HookEventName:            "PreToolUse",
+					PermissionDecision:       &deny,
+					PermissionDecisionReason: &reason,
+				},
+			}, nil
+		}
+		return nil, nil
+	}
+}
+
+// HookMatcher returns a PreToolUse HookMatcher that applies the guard to
+// all file tools and Bash.
+//
+// Example:
+//
+//	options.WithHook(types.HookEventPreToolUse, pg.HookMatcher())
+func (g *PathGuard) HookMatcher() types.HookMatcher {
+	matcher := FileTools
+	return types.HookMatcher{
+		Matcher: &matcher,
+		Hooks:   []types.HookCallback{g.PreToolUseHook()},
+	}
+}
+
+// paths extracts the file system paths referenced by a tool invocation.
+func (g *PathGuard) paths(toolName string, input map[string]any) ([]string, error) {
+	if toolName == "Bash" {
+		command, _ := input["command"].(string)
+		return bashPaths(command)
+	}
+
+	var paths []string
+	for _, field := range pathFields[toolName] {
+		value, _ := input[field].(string)
+		if value == "" {
+			continue
+		}
+		if field == "pattern" {
+			// Relative glob patterns are anchored at "path" and may climb out
+			// of it with "..", so check the literal prefix of the joined pattern
+			if !filepath.IsAbs(value) {
+				base, _ := input["path"].(string)
+				if base == "" {
+					base = g.cwd
+				}
+				value = filepath.Join(base, value)
+			}
+			value = globPrefix(value)
+		}
+		paths = append(paths, value)
+	}
+	return paths, nil
+}
+
+// resolve makes path absolute against the working directory and resolves symlinks.
+func (g *PathGuard) resolve(path string) string {
+	if strings.HasPrefix(path, "~") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(g.cwd, path)
+	}
+	return resolvePath(path)
+}
+
+// resolvePath cleans an absolute path and resolves symlinks in the longest
+// existing prefix, so paths to files that do not exist yet still resolve.
+func resolvePath(path string) string {
+	path = filepath.Clean(path)
+
+	var rest []string
+	current := path
+	for {
+		if resolved, err := filepath.EvalSymlinks(current); err == nil {
+			return filepath.Join(append([]string{resolved}, rest...)...)
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return path
+		}
+		rest = append([]string{filepath.Base(current)}, rest...)
+		current = parent
+	}
+}
+
+// globPrefix returns the leading directory of a glob pattern that contains
+// no wildcard characters.
+func globPrefix(pattern string) string {
+	idx := strings.IndexAny(pattern, "*?[{")
+	if idx < 0 {
+		return pattern
+	}
+	return filepath.Dir(pattern[:idx+1])
+}
+
+// bashPaths returns the absolute and home-relative paths referenced by a
+// shell command, including those in redirections and --flag=value arguments.
+func bashPaths(command string) ([]string, error) {
+	script, err := shell.Parse(command)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, word := range script.Words() {
+		if idx := strings.Index(word, "="); idx >= 0 && !strings.HasPrefix(word, "/") {
+			word = word[idx+1:]
+		}
+		if devicePaths[word] {
+			continue
+		}
+		if strings.HasPrefix(word, "/") || word == "~" || strings.HasPrefix(word, "~/") {
+			paths = append(paths, globPrefix(word))
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,50 @@
+package guard
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestPathGuardCheck(t *testing.T) {
+	cwd := t.TempDir()
+	g, err := NewPathGuard(cwd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		tool    string
+		input   map[string]any
+		allowed bool
+	}{
+		{"Read", map[string]any{"file_path": filepath.Join(cwd, "main.go")}, true},
+		{"Read", map[string]any{"file_path": "/etc/passwd"}, false},
+		{"Read", map[string]any{"file_path": filepath.Join(cwd, "../outside")}, false},
+		{"Glob", map[string]any{"pattern": "**/*.go"}, true},
+		{"Glob", map[string]any{"path": cwd, "pattern": "src/*.go"}, true},
+		{"Glob", map[string]any{"path": cwd, "pattern": "../../etc/*"}, false},
+		{"Glob", map[string]any{"pattern": "../*"}, false},
+		{"Glob", map[string]any{"path": cwd, "pattern": "*/../../*"}, false},
+		{"Glob", map[string]any{"pattern": "/etc/*"}, false},
+		{"Bash", map[string]any{"command": "cat /etc/passwd"}, false},
+	}
+
+	for _, tt := range tests {
+		err := g.Check(tt.tool, tt.input)
+		if allowed := err == nil; allowed != tt.allowed {
+			t.Errorf("Check(%s, %v) = %v, want allowed %v", tt.tool, tt.input, err, tt.allowed)
+		}
+	}
+}
+
+func TestPathGuardRoot(t *testing.T) {
+	g, err := NewPathGuard("/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/", "/etc/passwd", "relative/file"} {
+		if !g.Allowed(path) {
+			t.Errorf("Allowed(%q) = false under root /", path)
+		}
+	}
+}
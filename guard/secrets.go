@@ -0,0 +1,330 @@
+package guard
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/nabkey/claude-agent-sdk-go/types"
+)
+
+// SecretAction defines how a SecretScanner reacts to detected secrets.
+type SecretAction string
+
+const (
+	// SecretActionBlock blocks the prompt or denies the tool call. For tool
+	// results, which Claude has already received, it flags the result with
+	// a reason telling Claude not to repeat the secrets.
+	SecretActionBlock SecretAction = "block"
+	// SecretActionWarn lets the event through and injects a systemMessage warning.
+	SecretActionWarn SecretAction = "warn"
+	// SecretActionRedact rewrites tool inputs through PreToolUse UpdatedInput
+	// with an "ask" permission decision.
+	// Prompts and tool results cannot be rewritten by hooks, so for those
+	// events it behaves like SecretActionBlock.
+	SecretActionRedact SecretAction = "redact"
+)
+
+// SecretPattern is a named regular expression that identifies a secret.
+type SecretPattern struct {
+	Name   string
+	Regexp *regexp.Regexp
+}
+
+// DefaultSecretPatterns returns the built-in patterns for common credentials:
+// private keys, JWTs, AWS credentials, and Anthropic, OpenAI, GitHub, Slack,
+// Google and Stripe API keys, plus generic SECRET/TOKEN/PASSWORD assignments
+// as found in .env files.
+func DefaultSecretPatterns() []SecretPattern {
+	return []SecretPattern{
+		{"private-key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----(?:[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----)?`)},
+		{"aws-access-key-id", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+		{"aws-secret-access-key", regexp.MustCompile(`(?i)aws_secret_access_key["']?\s*[=:]\s*["']?[A-Za-z0-9/+=]{40}`)},
+		{"jwt", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+		{"anthropic-api-key", regexp.MustCompile(`\bsk-ant-[A-Za-z0-9_-]{20,}`)},
+		{"openai-api-key", regexp.MustCompile(`\bsk-(?:proj-)?[A-Za-z0-9_-]{20,}`)},
+		{"github-token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})`)},
+		{"slack-token", regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`)},
+		{"google-api-key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}`)},
+		{"stripe-key", regexp.MustCompile(`\b[rs]k_live_[0-9A-Za-z]{24,}`)},
+		{"generic-secret", regexp.MustCompile(`(?i)\b[A-Z0-9_]*(?:SECRET|TOKEN|PASSWORD|PASSWD|API_?KEY)[A-Z0-9_]*["']?\s*[=:]\s*["']?[^\s"']{8,}`)},
+	}
+}
+
+// SecretMatch describes a secret found in scanned text.
+type SecretMatch struct {
+	// Pattern is the name of the pattern that matched.
+	Pattern string
+	// Start and End are byte offsets of the match in the scanned text.
+	Start, End int
+}
+
+// SecretScanner scans prompts, tool inputs and tool responses for secrets
+// and provides hooks that act on them.
+//
+// Example:
+//
+//	scanner := guard.NewSecretScanner(guard.SecretActionBlock)
+//	if err := scanner.AddPattern("internal-token", `itk_[a-z0-9]{32}`); err != nil {
+//	    log.Fatal(err)
+//	}
+//
+//	options := &claude.AgentOptions{
+//	    Hooks: scanner.Hooks(),
+//	}
+type SecretScanner struct {
+	// Patterns are the expressions scanned for. NewSecretScanner populates
+	// them with DefaultSecretPatterns.
+	Patterns []SecretPattern
+
+	// Action controls how the hooks react to a detected secret.
+	Action SecretAction
+
+	// Replacement formats redacted text; %s is replaced with the pattern name.
+	// Defaults to "[REDACTED:%s]".
+	Replacement string
+}
+
+// NewSecretScanner creates a SecretScanner with the default patterns.
+func NewSecretScanner(action SecretAction) *SecretScanner {
+	return &SecretScanner{
+		Patterns: DefaultSecretPatterns(),
+		Action:   action,
+	}
+}
+
+// AddPattern compiles expr and adds it to the scanner under name.
+func (s *SecretScanner) AddPattern(name, expr string) error {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return fmt.Errorf("invalid secret pattern %s: %w", name, err)
+	}
+	s.Patterns = append(s.Patterns, SecretPattern{Name: name, Regexp: re})
+	return nil
+}
+
+// Scan returns the non-overlapping secrets found in text, in order of position.
+func (s *SecretScanner) Scan(text string) []SecretMatch {
+	var matches []SecretMatch
+	for _, p := range s.Patterns {
+		for _, loc := range p.Regexp.FindAllStringIndex(text, -1) {
+			matches = append(matches, SecretMatch{Pattern: p.Name, Start: loc[0], End: loc[1]})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Start != matches[j].Start {
+			return matches[i].Start < matches[j].Start
+		}
+		return matches[i].End > matches[j].End
+	})
+
+	// Drop matches contained in or overlapping an earlier one
+	result := matches[:0]
+	end := -1
+	for _, m := range matches {
+		if m.Start < end {
+			continue
+		}
+		result = append(result, m)
+		end = m.End
+	}
+	return result
+}
+
+// Redact replaces every secret in text and returns the result along with the matches.
+func (s *SecretScanner) Redact(text string) (string, []SecretMatch) {
+	matches := s.Scan(text)
+	if len(matches) == 0 {
+		return text, nil
+	}
+
+	replacement := s.Replacement
+	if replacement == "" {
+		replacement = "[REDACTED:%s]"
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		b.WriteString(text[last:m.Start])
+		b.WriteString(strings.ReplaceAll(replacement, "%s", m.Pattern))
+		last = m.End
+	}
+	b.WriteString(text[last:])
+	return b.String(), matches
+}
+
+// PreToolUseHook returns a hook callback that scans tool inputs. Block
+// denies the call, Warn adds a systemMessage, and Redact rewrites the input
+// through UpdatedInput and asks for permission to run the rewritten call.
+func (s *SecretScanner) PreToolUseHook() types.HookCallback {
+	return func(ctx context.Context, input types.HookInput, toolUseID *string, hookCtx *types.HookContext) (*types.HookOutput, error) {
+		pre, ok := input.(*types.PreToolUseHookInput)
+		if !ok {
+			return nil, nil
+		}
+
+		var found []SecretMatch
+		redacted, _ := s.redactValue(pre.ToolInput, &found).(map[string]any)
+		if len(found) == 0 {
+			return nil, nil
+		}
+
+		reason := fmt.Sprintf("%s input contains %s", pre.ToolName, describeMatches(found))
+		switch s.Action {
+		case SecretActionWarn:
+			return &types.HookOutput{SystemMessage: &reason}, nil
+		case SecretActionRedact:
+			// The CLI applies UpdatedInput only along with an allow or ask
+			// decision; ask keeps the rewritten call subject to approval.
+			ask := "ask"
+			return &types.HookOutput{
+				SystemMessage: &reason,
+				HookSpecificOutput: &types.PreToolUseHookSpecificOutput{
+					HookEventName:            "PreToolUse",
+					PermissionDecision:       &ask,
+					PermissionDecisionReason: &reason,
+					UpdatedInput:             redacted,
+				},
+			}, nil
+		default:
+			deny := "deny"
+			return &types.HookOutput{
+				HookSpecificOutput: &types.PreToolUseHookSpecificOutput{
+					HookEventName:            "PreToolUse",
+					PermissionDecision:       &deny,
+					PermissionDecisionReason: &reason,
+				},
+			}, nil
+		}
+	}
+}
+
+// PostToolUseHook returns a hook callback that scans tool responses. Warn
+// adds a systemMessage; Block and Redact return a "block" decision whose
+// reason tells Claude not to repeat the secrets. The tool has already run
+// and its output is already in Claude's context: a PostToolUse hook cannot
+// remove it. To keep secrets out of the conversation, deny the reads that
+// would expose them in PreToolUse, for example with a PathGuard.
+func (s *SecretScanner) PostToolUseHook() types.HookCallback {
+	return func(ctx context.Context, input types.HookInput, toolUseID *string, hookCtx *types.HookContext) (*types.HookOutput, error) {
+		post, ok := input.(*types.PostToolUseHookInput)
+		if !ok {
+			return nil, nil
+		}
+
+		found := s.Scan(stringify(post.ToolResponse))
+		if len(found) == 0 {
+			return nil, nil
+		}
+
+		message := fmt.Sprintf("%s output contains %s", post.ToolName, describeMatches(found))
+		if s.Action == SecretActionWarn {
+			return &types.HookOutput{SystemMessage: &message}, nil
+		}
+
+		block := "block"
+		reason := message + ". Do not repeat, log or transmit these values."
+		return &types.HookOutput{
+			Decision:      &block,
+			Reason:        &reason,
+			SystemMessage: &message,
+		}, nil
+	}
+}
+
+// UserPromptSubmitHook returns a hook callback that scans submitted prompts.
+// Warn adds a systemMessage; Block and Redact block the prompt.
+func (s *SecretScanner) UserPromptSubmitHook() types.HookCallback {
+	return func(ctx context.Context, input types.HookInput, toolUseID *string, hookCtx *types.HookContext) (*types.HookOutput, error) {
+		prompt, ok := input.(*types.UserPromptSubmitHookInput)
+		if !ok {
+			return nil, nil
+		}
+
+		found := s.Scan(prompt.Prompt)
+		if len(found) == 0 {
+			return nil, nil
+		}
+
+		message := fmt.Sprintf("prompt contains %s", describeMatches(found))
+		if s.Action == SecretActionWarn {
+			return &types.HookOutput{SystemMessage: &message}, nil
+		}
+
+		block := "block"
+		return &types.HookOutput{
+			Decision: &block,
+			Reason:   &message,
+		}, nil
+	}
+}
+
+// Hooks returns hook matchers for PreToolUse, PostToolUse and
+// UserPromptSubmit that can be assigned to AgentOptions.Hooks or merged
+// into an existing hook configuration.
+func (s *SecretScanner) Hooks() map[types.HookEvent][]types.HookMatcher {
+	return map[types.HookEvent][]types.HookMatcher{
+		types.HookEventPreToolUse:       {{Hooks: []types.HookCallback{s.PreToolUseHook()}}},
+		types.HookEventPostToolUse:      {{Hooks: []types.HookCallback{s.PostToolUseHook()}}},
+		types.HookEventUserPromptSubmit: {{Hooks: []types.HookCallback{s.UserPromptSubmitHook()}}},
+	}
+}
+
+// redactValue returns a copy of v with secrets in all strings redacted,
+// appending what it found to found.
+func (s *SecretScanner) redactValue(v any, found *[]SecretMatch) any {
+	switch val := v.(type) {
+	case string:
+		redacted, matches := s.Redact(val)
+		*found = append(*found, matches...)
+		return redacted
+	case map[string]any:
+		result := make(map[string]any, len(val))
+		for k, item := range val {
+			result[k] = s.redactValue(item, found)
+		}
+		return result
+	case []any:
+		result := make([]any, len(val))
+		for i, item := range val {
+			result[i] = s.redactValue(item, found)
+		}
+		return result
+	default:
+		return v
+	}
+}
+
+// stringify renders a tool response for scanning.
+func stringify(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// describeMatches summarizes matches by pattern name without revealing them.
+func describeMatches(matches []SecretMatch) string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, m := range matches {
+		if !seen[m.Pattern] {
+			seen[m.Pattern] = true
+			names = append(names, m.Pattern)
+		}
+	}
+	noun := "secret"
+	if len(matches) > 1 {
+		noun = "secrets"
+	}
+	return fmt.Sprintf("%d %s (%s)", len(matches), noun, strings.Join(names, ", "))
+}
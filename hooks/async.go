@@ -0,0 +1,46 @@
+package hooks
+
+import (
+	"context"
+
+	"github.com/nabkey/claude-agent-sdk-go/types"
+)
+
+// AsyncFunc is hook work that runs in the background after the CLI has been
+// answered. Its context is cancelled when the client closes.
+type AsyncFunc func(ctx context.Context, input types.HookInput, toolUseID *string) (*types.HookOutput, error)
+
+// Async returns a hook callback that answers the CLI immediately with
+// `async: true` and runs fn in the background. Because the CLI no longer
+// waits for it, fn cannot block or deny anything; its SystemMessage and
+// additional context are delivered with the next hook response, typically
+// the next turn's UserPromptSubmit. Completion and errors are reported
+// through AgentOptions.OnAsyncHookResult.
+//
+// When the callback is invoked outside a client, fn runs synchronously and
+// its output is returned as is.
+//
+// Example:
+//
+//	lint := hooks.Async(func(ctx context.Context, in types.HookInput, _ *string) (*types.HookOutput, error) {
+//	    report, err := runLinter(ctx)
+//	    if err != nil {
+//	        return nil, err
+//	    }
+//	    return &types.HookOutput{SystemMessage: &report}, nil
+//	})
+//
+//	options.WithHooks(map[types.HookEvent][]types.HookMatcher{
+//	    types.HookEventPostToolUse: {{Hooks: []types.HookCallback{lint}}},
+//	})
+func Async(fn AsyncFunc) types.HookCallback {
+	return func(ctx context.Context, input types.HookInput, toolUseID *string, hookCtx *types.HookContext) (*types.HookOutput, error) {
+		if hookCtx == nil || hookCtx.Go == nil {
+			return fn(ctx, input, toolUseID)
+		}
+		hookCtx.Go(func(ctx context.Context) (*types.HookOutput, error) {
+			return fn(ctx, input, toolUseID)
+		})
+		return &types.HookOutput{Async: boolPtr(true)}, nil
+	}
+}
@@ -0,0 +1,285 @@
+package hooks
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/nabkey/claude-agent-sdk-go/types"
+)
+
+// Chain runs callbacks in order and merges their outputs with MergeOutputs.
+//
+// A PreToolUse UpdatedInput returned by one callback is passed as the tool
+// input to the callbacks after it. The chain short-circuits as soon as an
+// output is final: it stops the session, blocks, or denies a tool call.
+func Chain(callbacks ...types.HookCallback) types.HookCallback {
+	return func(ctx context.Context, input types.HookInput, toolUseID *string, hookCtx *types.HookContext) (*types.HookOutput, error) {
+		var outputs []*types.HookOutput
+		for _, cb := range callbacks {
+			out, err := cb(ctx, input, toolUseID, hookCtx)
+			if err != nil {
+				return nil, err
+			}
+			if out == nil {
+				continue
+			}
+			outputs = append(outputs, out)
+			if IsFinal(out) {
+				break
+			}
+			input = withUpdatedInput(input, out)
+		}
+		return MergeOutputs(outputs...), nil
+	}
+}
+
+// Parallel runs callbacks concurrently and merges their outputs in argument
+// order. If any callback fails, the context passed to the others is
+// cancelled and the first error is returned.
+func Parallel(callbacks ...types.HookCallback) types.HookCallback {
+	return func(ctx context.Context, input types.HookInput, toolUseID *string, hookCtx *types.HookContext) (*types.HookOutput, error) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		outputs := make([]*types.HookOutput, len(callbacks))
+		var firstErr error
+		var errOnce sync.Once
+		var wg sync.WaitGroup
+
+		for i, cb := range callbacks {
+			wg.Add(1)
+			go func(i int, cb types.HookCallback) {
+				defer wg.Done()
+				out, err := cb(ctx, input, toolUseID, hookCtx)
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					return
+				}
+				outputs[i] = out
+			}(i, cb)
+		}
+		wg.Wait()
+
+		if firstErr != nil {
+			return nil, firstErr
+		}
+		return MergeOutputs(outputs...), nil
+	}
+}
+
+// When runs callback only for inputs accepted by predicate.
+func When(predicate func(types.HookInput) bool, callback types.HookCallback) types.HookCallback {
+	return func(ctx context.Context, input types.HookInput, toolUseID *string, hookCtx *types.HookContext) (*types.HookOutput, error) {
+		if !predicate(input) {
+			return nil, nil
+		}
+		return callback(ctx, input, toolUseID, hookCtx)
+	}
+}
+
+// IsFinal reports whether an output ends further processing: it stops the
+// session, blocks, or denies a tool call or permission request.
+func IsFinal(out *types.HookOutput) bool {
+	if out == nil {
+		return false
+	}
+	if out.Continue != nil && !*out.Continue {
+		return true
+	}
+	if out.Decision != nil && *out.Decision == "block" {
+		return true
+	}
+	switch hso := out.HookSpecificOutput.(type) {
+	case *types.PreToolUseHookSpecificOutput:
+		return hso.PermissionDecision != nil && *hso.PermissionDecision == string(types.PermissionBehaviorDeny)
+	case *types.PermissionRequestHookSpecificOutput:
+		return hso.Decision != nil && hso.Decision.Behavior == types.PermissionBehaviorDeny
+	}
+	return false
+}
+
+// MergeOutputs combines several hook outputs into one, choosing the most
+// restrictive outcome:
+//   - continue is false if any output sets it to false
+//   - a "block" decision wins; reasons, stop reasons, system messages and
+//     additional context are joined with newlines
+//   - PreToolUse permission decisions resolve as deny > ask > allow, and the
+//     last UpdatedInput wins
+//   - the result is async only if every output is; otherwise the CLI would
+//     stop waiting and drop a synchronous deny or block
+//
+// Nil outputs are skipped; the result is nil if all outputs are nil.
+func MergeOutputs(outputs ...*types.HookOutput) *types.HookOutput {
+	var merged *types.HookOutput
+	sync := false
+	for _, out := range outputs {
+		if out == nil {
+			continue
+		}
+		if merged == nil {
+			merged = &types.HookOutput{}
+		}
+		if out.Async == nil || !*out.Async {
+			sync = true
+		}
+
+		if out.Continue != nil && (merged.Continue == nil || !*out.Continue) {
+			merged.Continue = boolPtr(*out.Continue)
+		}
+		if out.SuppressOutput != nil && *out.SuppressOutput {
+			merged.SuppressOutput = boolPtr(true)
+		}
+		if out.Decision != nil && (merged.Decision == nil || *out.Decision == "block") {
+			merged.Decision = stringPtr(*out.Decision)
+		}
+		merged.StopReason = joinPtr(merged.StopReason, out.StopReason)
+		merged.Reason = joinPtr(merged.Reason, out.Reason)
+		merged.SystemMessage = joinPtr(merged.SystemMessage, out.SystemMessage)
+		if out.Async != nil {
+			merged.Async = out.Async
+		}
+		if out.AsyncTimeout != nil {
+			merged.AsyncTimeout = out.AsyncTimeout
+		}
+
+		merged.HookSpecificOutput = mergeSpecific(merged.HookSpecificOutput, out.HookSpecificOutput)
+	}
+	if merged != nil && sync {
+		merged.Async = nil
+		merged.AsyncTimeout = nil
+	}
+	return merged
+}
+
+// permissionRank orders permission decisions by restrictiveness.
+var permissionRank = map[string]int{
+	string(types.PermissionBehaviorAllow): 1,
+	string(types.PermissionBehaviorAsk):   2,
+	string(types.PermissionBehaviorDeny):  3,
+}
+
+// mergeSpecific merges hook-specific outputs of the same type. When the
+// types differ, the existing value is kept.
+func mergeSpecific(into, from types.HookSpecificOutput) types.HookSpecificOutput {
+	if from == nil {
+		return into
+	}
+	if into == nil {
+		return cloneSpecific(from)
+	}
+
+	switch dst := into.(type) {
+	case *types.PreToolUseHookSpecificOutput:
+		src, ok := from.(*types.PreToolUseHookSpecificOutput)
+		if !ok {
+			return into
+		}
+		if src.PermissionDecision != nil {
+			if dst.PermissionDecision == nil || permissionRank[*src.PermissionDecision] > permissionRank[*dst.PermissionDecision] {
+				dst.PermissionDecision = src.PermissionDecision
+				dst.PermissionDecisionReason = src.PermissionDecisionReason
+			} else if *src.PermissionDecision == *dst.PermissionDecision {
+				dst.PermissionDecisionReason = joinPtr(dst.PermissionDecisionReason, src.PermissionDecisionReason)
+			}
+		}
+		if src.UpdatedInput != nil {
+			dst.UpdatedInput = src.UpdatedInput
+		}
+
+	case *types.PostToolUseHookSpecificOutput:
+		if src, ok := from.(*types.PostToolUseHookSpecificOutput); ok {
+			dst.AdditionalContext = joinPtr(dst.AdditionalContext, src.AdditionalContext)
+		}
+
+	case *types.UserPromptSubmitHookSpecificOutput:
+		if src, ok := from.(*types.UserPromptSubmitHookSpecificOutput); ok {
+			dst.AdditionalContext = joinPtr(dst.AdditionalContext, src.AdditionalContext)
+		}
+
+	case *types.SessionStartHookSpecificOutput:
+		if src, ok := from.(*types.SessionStartHookSpecificOutput); ok {
+			dst.AdditionalContext = joinPtr(dst.AdditionalContext, src.AdditionalContext)
+		}
+
+	case *types.SubagentStartHookSpecificOutput:
+		if src, ok := from.(*types.SubagentStartHookSpecificOutput); ok {
+			dst.AdditionalContext = joinPtr(dst.AdditionalContext, src.AdditionalContext)
+		}
+
+	case *types.NotificationHookSpecificOutput:
+		if src, ok := from.(*types.NotificationHookSpecificOutput); ok {
+			dst.AdditionalContext = joinPtr(dst.AdditionalContext, src.AdditionalContext)
+		}
+
+	case *types.PermissionRequestHookSpecificOutput:
+		src, ok := from.(*types.PermissionRequestHookSpecificOutput)
+		if !ok || src.Decision == nil {
+			return into
+		}
+		if dst.Decision == nil || permissionRank[string(src.Decision.Behavior)] > permissionRank[string(dst.Decision.Behavior)] {
+			dst.Decision = src.Decision
+		}
+	}
+	return into
+}
+
+// cloneSpecific copies a hook-specific output so merging never mutates a
+// callback's return value.
+func cloneSpecific(hso types.HookSpecificOutput) types.HookSpecificOutput {
+	switch v := hso.(type) {
+	case *types.PreToolUseHookSpecificOutput:
+		c := *v
+		return &c
+	case *types.PostToolUseHookSpecificOutput:
+		c := *v
+		return &c
+	case *types.UserPromptSubmitHookSpecificOutput:
+		c := *v
+		return &c
+	case *types.SessionStartHookSpecificOutput:
+		c := *v
+		return &c
+	case *types.SubagentStartHookSpecificOutput:
+		c := *v
+		return &c
+	case *types.NotificationHookSpecificOutput:
+		c := *v
+		return &c
+	case *types.PermissionRequestHookSpecificOutput:
+		c := *v
+		return &c
+	}
+	return hso
+}
+
+// withUpdatedInput returns input with the tool input replaced when out
+// carries a PreToolUse UpdatedInput.
+func withUpdatedInput(input types.HookInput, out *types.HookOutput) types.HookInput {
+	pre, ok := input.(*types.PreToolUseHookInput)
+	if !ok {
+		return input
+	}
+	hso, ok := out.HookSpecificOutput.(*types.PreToolUseHookSpecificOutput)
+	if !ok || hso.UpdatedInput == nil {
+		return input
+	}
+	updated := *pre
+	updated.ToolInput = hso.UpdatedInput
+	return &updated
+}
+
+// joinPtr joins two optional strings with a newline.
+func joinPtr(a, b *string) *string {
+	switch {
+	case b == nil || *b == "":
+		return a
+	case a == nil || *a == "":
+		return stringPtr(*b)
+	default:
+		return stringPtr(strings.Join([]string{*a, *b}, "\n"))
+	}
+}
@@ -0,0 +1,47 @@
+package hooks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nabkey/claude-agent-sdk-go/types"
+)
+
+func TestCombinatorsAsyncDoesNotOverrideDeny(t *testing.T) {
+	background := Async(func(ctx context.Context, input types.HookInput, toolUseID *string) (*types.HookOutput, error) {
+		return nil, nil
+	})
+	deny := PreToolUse(func(ctx context.Context, input *types.PreToolUseHookInput) (*PreToolUseDecision, error) {
+		return Deny("not allowed"), nil
+	})
+	allow := PreToolUse(func(ctx context.Context, input *types.PreToolUseHookInput) (*PreToolUseDecision, error) {
+		return Allow(), nil
+	})
+
+	tests := []struct {
+		name     string
+		callback types.HookCallback
+		async    bool
+		denied   bool
+	}{
+		{"Chain(Async, deny)", Chain(background, deny), false, true},
+		{"Chain(deny, Async)", Chain(deny, background), false, true},
+		{"Parallel(Async, deny)", Parallel(background, deny), false, true},
+		{"Chain(Async, allow)", Chain(background, allow), false, false},
+		{"Chain(Async, Async)", Chain(background, background), true, false},
+	}
+
+	for _, tt := range tests {
+		hookCtx := &types.HookContext{Go: func(fn types.AsyncHookFunc) {}}
+		out, err := tt.callback(context.Background(), &types.PreToolUseHookInput{}, nil, hookCtx)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if async := out.Async != nil && *out.Async; async != tt.async {
+			t.Errorf("%s: async = %v, want %v", tt.name, async, tt.async)
+		}
+		if denied := IsFinal(out); denied != tt.denied {
+			t.Errorf("%s: denied = %v, want %v", tt.name, denied, tt.denied)
+		}
+	}
+}
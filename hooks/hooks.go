@@ -0,0 +1,516 @@
+// Package hooks provides typed hook registration and combinators on top of
+// types.HookCallback.
+//
+// Instead of writing a HookCallback that type-switches on HookInput and
+// assembles a HookOutput by hand, register a typed function per event:
+//
+//	options := &claude.AgentOptions{}
+//	options.WithHooks(hooks.Build(
+//	    hooks.OnPreToolUse("Bash", func(ctx context.Context, in *types.PreToolUseHookInput) (*hooks.PreToolUseDecision, error) {
+//	        if strings.Contains(in.ToolInput["command"].(string), "rm -rf") {
+//	            return hooks.Deny("rm -rf is not allowed"), nil
+//	        }
+//	        return nil, nil
+//	    }),
+//	    hooks.OnPostToolUse("", logToolResult),
+//	))
+package hooks
+
+import (
+	"context"
+
+	"github.com/nabkey/claude-agent-sdk-go/types"
+)
+
+// Hook pairs an event with a matcher, ready to be added to AgentOptions.Hooks.
+type Hook struct {
+	Event   types.HookEvent
+	Matcher types.HookMatcher
+}
+
+// Build groups hooks by event into the map used by AgentOptions.Hooks.
+func Build(hooks ...Hook) map[types.HookEvent][]types.HookMatcher {
+	config := make(map[types.HookEvent][]types.HookMatcher)
+	for _, h := range hooks {
+		config[h.Event] = append(config[h.Event], h.Matcher)
+	}
+	return config
+}
+
+// Merge combines several hook configurations. Matchers for the same event
+// are concatenated in argument order.
+func Merge(configs ...map[types.HookEvent][]types.HookMatcher) map[types.HookEvent][]types.HookMatcher {
+	merged := make(map[types.HookEvent][]types.HookMatcher)
+	for _, config := range configs {
+		for event, matchers := range config {
+			merged[event] = append(merged[event], matchers...)
+		}
+	}
+	return merged
+}
+
+// WithTimeout returns a copy of the hook with its matcher timeout set in seconds.
+func (h Hook) WithTimeout(seconds float64) Hook {
+	h.Matcher.Timeout = &seconds
+	return h
+}
+
+// newHook builds a Hook for event; an empty matcher matches everything.
+func newHook(event types.HookEvent, matcher string, callback types.HookCallback) Hook {
+	m := types.HookMatcher{Hooks: []types.HookCallback{callback}}
+	if matcher != "" {
+		m.Matcher = &matcher
+	}
+	return Hook{Event: event, Matcher: m}
+}
+
+// Output holds the result fields shared by every hook event.
+type Output struct {
+	// Stop ends the session after the hook (continue: false).
+	Stop bool
+	// StopReason is shown to the user when Stop is set.
+	StopReason string
+	// SuppressOutput hides the hook's output from the transcript.
+	SuppressOutput bool
+	// SystemMessage is a warning shown to the user.
+	SystemMessage string
+}
+
+// apply copies the common fields onto out.
+func (o *Output) apply(out *types.HookOutput) {
+	if o.Stop {
+		out.Continue = boolPtr(false)
+	}
+	if o.StopReason != "" {
+		out.StopReason = stringPtr(o.StopReason)
+	}
+	if o.SuppressOutput {
+		out.SuppressOutput = boolPtr(true)
+	}
+	if o.SystemMessage != "" {
+		out.SystemMessage = stringPtr(o.SystemMessage)
+	}
+}
+
+// PreToolUseDecision is the typed result of a PreToolUse hook.
+type PreToolUseDecision struct {
+	Output
+	// Permission is "allow", "deny" or "ask"; empty leaves the decision to
+	// other hooks and the regular permission flow.
+	Permission types.PermissionBehavior
+	// Reason explains the permission decision.
+	Reason string
+	// UpdatedInput replaces the tool input before execution.
+	UpdatedInput map[string]any
+}
+
+// Allow returns a decision that approves the tool call.
+func Allow() *PreToolUseDecision {
+	return &PreToolUseDecision{Permission: types.PermissionBehaviorAllow}
+}
+
+// Deny returns a decision that rejects the tool call with reason.
+func Deny(reason string) *PreToolUseDecision {
+	return &PreToolUseDecision{Permission: types.PermissionBehaviorDeny, Reason: reason}
+}
+
+// Ask returns a decision that asks the user to confirm the tool call.
+func Ask(reason string) *PreToolUseDecision {
+	return &PreToolUseDecision{Permission: types.PermissionBehaviorAsk, Reason: reason}
+}
+
+// HookOutput converts the decision to a types.HookOutput.
+func (d *PreToolUseDecision) HookOutput() *types.HookOutput {
+	if d == nil {
+		return nil
+	}
+	out := &types.HookOutput{}
+	d.Output.apply(out)
+
+	hso := &types.PreToolUseHookSpecificOutput{
+		HookEventName: string(types.HookEventPreToolUse),
+		UpdatedInput:  d.UpdatedInput,
+	}
+	if d.Permission != "" {
+		hso.PermissionDecision = stringPtr(string(d.Permission))
+	}
+	if d.Reason != "" {
+		hso.PermissionDecisionReason = stringPtr(d.Reason)
+	}
+	if hso.PermissionDecision != nil || hso.PermissionDecisionReason != nil || hso.UpdatedInput != nil {
+		out.HookSpecificOutput = hso
+	}
+	return out
+}
+
+// PostToolUseDecision is the typed result of a PostToolUse hook.
+type PostToolUseDecision struct {
+	Output
+	// Block feeds Reason back to Claude as feedback on the tool result.
+	Block bool
+	// Reason explains why the result was blocked.
+	Reason string
+	// AdditionalContext is added to the conversation for Claude.
+	AdditionalContext string
+}
+
+// HookOutput converts the decision to a types.HookOutput.
+func (d *PostToolUseDecision) HookOutput() *types.HookOutput {
+	if d == nil {
+		return nil
+	}
+	out := &types.HookOutput{}
+	d.Output.apply(out)
+	applyBlock(out, d.Block, d.Reason)
+	if d.AdditionalContext != "" {
+		out.HookSpecificOutput = &types.PostToolUseHookSpecificOutput{
+			HookEventName:     string(types.HookEventPostToolUse),
+			AdditionalContext: stringPtr(d.AdditionalContext),
+		}
+	}
+	return out
+}
+
+// UserPromptSubmitDecision is the typed result of a UserPromptSubmit hook.
+type UserPromptSubmitDecision struct {
+	Output
+	// Block rejects the prompt; Reason is shown to the user.
+	Block bool
+	// Reason explains why the prompt was blocked.
+	Reason string
+	// AdditionalContext is added to the conversation alongside the prompt.
+	AdditionalContext string
+}
+
+// HookOutput converts the decision to a types.HookOutput.
+func (d *UserPromptSubmitDecision) HookOutput() *types.HookOutput {
+	if d == nil {
+		return nil
+	}
+	out := &types.HookOutput{}
+	d.Output.apply(out)
+	applyBlock(out, d.Block, d.Reason)
+	if d.AdditionalContext != "" {
+		out.HookSpecificOutput = &types.UserPromptSubmitHookSpecificOutput{
+			HookEventName:     string(types.HookEventUserPromptSubmit),
+			AdditionalContext: stringPtr(d.AdditionalContext),
+		}
+	}
+	return out
+}
+
+// StopDecision is the typed result of a Stop or SubagentStop hook.
+type StopDecision struct {
+	Output
+	// Block prevents Claude from stopping; Reason tells it how to continue.
+	Block bool
+	// Reason is required when Block is set.
+	Reason string
+}
+
+// HookOutput converts the decision to a types.HookOutput.
+func (d *StopDecision) HookOutput() *types.HookOutput {
+	if d == nil {
+		return nil
+	}
+	out := &types.HookOutput{}
+	d.Output.apply(out)
+	applyBlock(out, d.Block, d.Reason)
+	return out
+}
+
+// ContextDecision is the typed result of SessionStart, SubagentStart and
+// Notification hooks, which can add context to the conversation.
+type ContextDecision struct {
+	Output
+	// AdditionalContext is added to the conversation for Claude.
+	AdditionalContext string
+}
+
+// hookOutput converts the decision to a types.HookOutput for event.
+func (d *ContextDecision) hookOutput(event types.HookEvent) *types.HookOutput {
+	if d == nil {
+		return nil
+	}
+	out := &types.HookOutput{}
+	d.Output.apply(out)
+	if d.AdditionalContext == "" {
+		return out
+	}
+
+	ctx := stringPtr(d.AdditionalContext)
+	switch event {
+	case types.HookEventSessionStart:
+		out.HookSpecificOutput = &types.SessionStartHookSpecificOutput{HookEventName: string(event), AdditionalContext: ctx}
+	case types.HookEventSubagentStart:
+		out.HookSpecificOutput = &types.SubagentStartHookSpecificOutput{HookEventName: string(event), AdditionalContext: ctx}
+	case types.HookEventNotification:
+		out.HookSpecificOutput = &types.NotificationHookSpecificOutput{HookEventName: string(event), AdditionalContext: ctx}
+	}
+	return out
+}
+
+// Typed callback signatures for each hook event.
+type (
+	PreToolUseFunc        func(ctx context.Context, input *types.PreToolUseHookInput) (*PreToolUseDecision, error)
+	PostToolUseFunc       func(ctx context.Context, input *types.PostToolUseHookInput) (*PostToolUseDecision, error)
+	UserPromptSubmitFunc  func(ctx context.Context, input *types.UserPromptSubmitHookInput) (*UserPromptSubmitDecision, error)
+	StopFunc              func(ctx context.Context, input *types.StopHookInput) (*StopDecision, error)
+	SubagentStopFunc      func(ctx context.Context, input *types.SubagentStopHookInput) (*StopDecision, error)
+	PreCompactFunc        func(ctx context.Context, input *types.PreCompactHookInput) (*Output, error)
+	NotificationFunc      func(ctx context.Context, input *types.NotificationHookInput) (*ContextDecision, error)
+	SessionStartFunc      func(ctx context.Context, input *types.SessionStartHookInput) (*ContextDecision, error)
+	SessionEndFunc        func(ctx context.Context, input *types.SessionEndHookInput) (*Output, error)
+	SubagentStartFunc     func(ctx context.Context, input *types.SubagentStartHookInput) (*ContextDecision, error)
+	PermissionRequestFunc func(ctx context.Context, input *types.PermissionRequestHookInput) (*types.PermissionRequestDecision, error)
+)
+
+// OnPreToolUse registers fn for PreToolUse events on tools matching matcher
+// (e.g. "Bash" or "Write|Edit"; empty matches all tools).
+func OnPreToolUse(matcher string, fn PreToolUseFunc) Hook {
+	return newHook(types.HookEventPreToolUse, matcher, PreToolUse(fn))
+}
+
+// OnPostToolUse registers fn for PostToolUse events on tools matching matcher.
+func OnPostToolUse(matcher string, fn PostToolUseFunc) Hook {
+	return newHook(types.HookEventPostToolUse, matcher, PostToolUse(fn))
+}
+
+// OnUserPromptSubmit registers fn for UserPromptSubmit events.
+func OnUserPromptSubmit(fn UserPromptSubmitFunc) Hook {
+	return newHook(types.HookEventUserPromptSubmit, "", UserPromptSubmit(fn))
+}
+
+// OnStop registers fn for Stop events.
+func OnStop(fn StopFunc) Hook {
+	return newHook(types.HookEventStop, "", Stop(fn))
+}
+
+// OnSubagentStop registers fn for SubagentStop events.
+func OnSubagentStop(fn SubagentStopFunc) Hook {
+	return newHook(types.HookEventSubagentStop, "", SubagentStop(fn))
+}
+
+// OnPreCompact registers fn for PreCompact events. The matcher selects the
+// trigger ("manual" or "auto"); empty matches both.
+func OnPreCompact(matcher string, fn PreCompactFunc) Hook {
+	return newHook(types.HookEventPreCompact, matcher, PreCompact(fn))
+}
+
+// OnNotification registers fn for Notification events. The matcher selects
+// the notification type; empty matches all notifications.
+func OnNotification(matcher string, fn NotificationFunc) Hook {
+	return newHook(types.HookEventNotification, matcher, Notification(fn))
+}
+
+// OnSessionStart registers fn for SessionStart events. The matcher selects
+// the source ("startup", "resume", "clear" or "compact"); empty matches all.
+func OnSessionStart(matcher string, fn SessionStartFunc) Hook {
+	return newHook(types.HookEventSessionStart, matcher, SessionStart(fn))
+}
+
+// OnSessionEnd registers fn for SessionEnd events.
+func OnSessionEnd(fn SessionEndFunc) Hook {
+	return newHook(types.HookEventSessionEnd, "", SessionEnd(fn))
+}
+
+// OnSubagentStart registers fn for SubagentStart events. The matcher selects
+// the agent type; empty matches all subagents.
+func OnSubagentStart(matcher string, fn SubagentStartFunc) Hook {
+	return newHook(types.HookEventSubagentStart, matcher, SubagentStart(fn))
+}
+
+// OnPermissionRequest registers fn for PermissionRequest events on tools
+// matching matcher. Returning a nil decision leaves the request to the user.
+func OnPermissionRequest(matcher string, fn PermissionRequestFunc) Hook {
+	return newHook(types.HookEventPermissionRequest, matcher, PermissionRequest(fn))
+}
+
+// PreToolUse adapts a typed PreToolUse function to a HookCallback. Inputs
+// for other events are ignored.
+func PreToolUse(fn PreToolUseFunc) types.HookCallback {
+	return func(ctx context.Context, input types.HookInput, toolUseID *string, hookCtx *types.HookContext) (*types.HookOutput, error) {
+		in, ok := input.(*types.PreToolUseHookInput)
+		if !ok {
+			return nil, nil
+		}
+		d, err := fn(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		return d.HookOutput(), nil
+	}
+}
+
+// PostToolUse adapts a typed PostToolUse function to a HookCallback.
+func PostToolUse(fn PostToolUseFunc) types.HookCallback {
+	return func(ctx context.Context, input types.HookInput, toolUseID *string, hookCtx *types.HookContext) (*types.HookOutput, error) {
+		in, ok := input.(*types.PostToolUseHookInput)
+		if !ok {
+			return nil, nil
+		}
+		d, err := fn(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		return d.HookOutput(), nil
+	}
+}
+
+// UserPromptSubmit adapts a typed UserPromptSubmit function to a HookCallback.
+func UserPromptSubmit(fn UserPromptSubmitFunc) types.HookCallback {
+	return func(ctx context.Context, input types.HookInput, toolUseID *string, hookCtx *types.HookContext) (*types.HookOutput, error) {
+		in, ok := input.(*types.UserPromptSubmitHookInput)
+		if !ok {
+			return nil, nil
+		}
+		d, err := fn(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		return d.HookOutput(), nil
+	}
+}
+
+// Stop adapts a typed Stop function to a HookCallback.
+func Stop(fn StopFunc) types.HookCallback {
+	return func(ctx context.Context, input types.HookInput, toolUseID *string, hookCtx *types.HookContext) (*types.HookOutput, error) {
+		in, ok := input.(*types.StopHookInput)
+		if !ok {
+			return nil, nil
+		}
+		d, err := fn(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		return d.HookOutput(), nil
+	}
+}
+
+// SubagentStop adapts a typed SubagentStop function to a HookCallback.
+func SubagentStop(fn SubagentStopFunc) types.HookCallback {
+	return func(ctx context.Context, input types.HookInput, toolUseID *string, hookCtx *types.HookContext) (*types.HookOutput, error) {
+		in, ok := input.(*types.SubagentStopHookInput)
+		if !ok {
+			return nil, nil
+		}
+		d, err := fn(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		return d.HookOutput(), nil
+	}
+}
+
+// PreCompact adapts a typed PreCompact function to a HookCallback.
+func PreCompact(fn PreCompactFunc) types.HookCallback {
+	return func(ctx context.Context, input types.HookInput, toolUseID *string, hookCtx *types.HookContext) (*types.HookOutput, error) {
+		in, ok := input.(*types.PreCompactHookInput)
+		if !ok {
+			return nil, nil
+		}
+		o, err := fn(ctx, in)
+		if err != nil || o == nil {
+			return nil, err
+		}
+		out := &types.HookOutput{}
+		o.apply(out)
+		return out, nil
+	}
+}
+
+// Notification adapts a typed Notification function to a HookCallback.
+func Notification(fn NotificationFunc) types.HookCallback {
+	return func(ctx context.Context, input types.HookInput, toolUseID *string, hookCtx *types.HookContext) (*types.HookOutput, error) {
+		in, ok := input.(*types.NotificationHookInput)
+		if !ok {
+			return nil, nil
+		}
+		d, err := fn(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		return d.hookOutput(types.HookEventNotification), nil
+	}
+}
+
+// SessionStart adapts a typed SessionStart function to a HookCallback.
+func SessionStart(fn SessionStartFunc) types.HookCallback {
+	return func(ctx context.Context, input types.HookInput, toolUseID *string, hookCtx *types.HookContext) (*types.HookOutput, error) {
+		in, ok := input.(*types.SessionStartHookInput)
+		if !ok {
+			return nil, nil
+		}
+		d, err := fn(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		return d.hookOutput(types.HookEventSessionStart), nil
+	}
+}
+
+// SessionEnd adapts a typed SessionEnd function to a HookCallback.
+func SessionEnd(fn SessionEndFunc) types.HookCallback {
+	return func(ctx context.Context, input types.HookInput, toolUseID *string, hookCtx *types.HookContext) (*types.HookOutput, error) {
+		in, ok := input.(*types.SessionEndHookInput)
+		if !ok {
+			return nil, nil
+		}
+		o, err := fn(ctx, in)
+		if err != nil || o == nil {
+			return nil, err
+		}
+		out := &types.HookOutput{}
+		o.apply(out)
+		return out, nil
+	}
+}
+
+// SubagentStart adapts a typed SubagentStart function to a HookCallback.
+func SubagentStart(fn SubagentStartFunc) types.HookCallback {
+	return func(ctx context.Context, input types.HookInput, toolUseID *string, hookCtx *types.HookContext) (*types.HookOutput, error) {
+		in, ok := input.(*types.SubagentStartHookInput)
+		if !ok {
+			return nil, nil
+		}
+		d, err := fn(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		return d.hookOutput(types.HookEventSubagentStart), nil
+	}
+}
+
+// PermissionRequest adapts a typed PermissionRequest function to a HookCallback.
+func PermissionRequest(fn PermissionRequestFunc) types.HookCallback {
+	return func(ctx context.Context, input types.HookInput, toolUseID *string, hookCtx *types.HookContext) (*types.HookOutput, error) {
+		in, ok := input.(*types.PermissionRequestHookInput)
+		if !ok {
+			return nil, nil
+		}
+		d, err := fn(ctx, in)
+		if err != nil || d == nil {
+			return nil, err
+		}
+		return &types.HookOutput{
+			HookSpecificOutput: &types.PermissionRequestHookSpecificOutput{
+				HookEventName: string(types.HookEventPermissionRequest),
+				Decision:      d,
+			},
+		}, nil
+	}
+}
+
+// applyBlock sets the block decision and reason on out.
+func applyBlock(out *types.HookOutput, block bool, reason string) {
+	if block {
+		out.Decision = stringPtr("block")
+	}
+	if reason != "" {
+		out.Reason = stringPtr(reason)
+	}
+}
+
+func stringPtr(s string) *string { return &s }
+
+func boolPtr(b bool) *bool { return &b }
@@ -0,0 +1,165 @@
+package claude
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nabkey/claude-agent-sdk-go/internal/protocol"
+	"github.com/nabkey/claude-agent-sdk-go/types"
+)
+
+// Size limits for attachments, matching the limits of the Claude API.
+const (
+	// MaxImageSize is the largest image accepted, in bytes.
+	MaxImageSize = 5 << 20
+	// MaxDocumentSize is the largest document accepted, in bytes.
+	MaxDocumentSize = 32 << 20
+)
+
+// imageMediaTypes are the image formats accepted by the API.
+var imageMediaTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// NewUserMessage creates a user message with a text prompt, for use with
+// ConnectStream.
+func NewUserMessage(prompt string) types.UserInputMessage {
+	return newUserInput(prompt)
+}
+
+// NewUserContentMessage creates a user message made of content blocks, for
+// use with ConnectStream.
+func NewUserContentMessage(content ...types.ContentBlock) (types.UserInputMessage, error) {
+	blocks, err := protocol.MarshalContentBlocks(content)
+	if err != nil {
+		return types.UserInputMessage{}, err
+	}
+	return newUserInput(blocks), nil
+}
+
+// newUserInput wraps content in a user message for the default session.
+func newUserInput(content any) types.UserInputMessage {
+	return types.UserInputMessage{
+		Type: "user",
+		Message: types.UserInputInner{
+			Role:    "user",
+			Content: content,
+		},
+		SessionID: "default",
+	}
+}
+
+// NewImage creates a base64 image block from raw image data. The media type
+// is detected from the content; JPEG, PNG, GIF and WebP are supported.
+func NewImage(data []byte) (*types.ImageBlock, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("image is empty")
+	}
+	if len(data) > MaxImageSize {
+		return nil, fmt.Errorf("image is %d bytes, larger than the %d byte limit", len(data), MaxImageSize)
+	}
+
+	mediaType := detectMediaType(data)
+	if !imageMediaTypes[mediaType] {
+		return nil, fmt.Errorf("unsupported image type %s", mediaType)
+	}
+
+	return &types.ImageBlock{
+		Source: types.ContentSource{
+			Type:      "base64",
+			MediaType: mediaType,
+			Data:      base64.StdEncoding.EncodeToString(data),
+		},
+	}, nil
+}
+
+// NewImageFile reads an image file and creates a base64 image block.
+func NewImageFile(path string) (*types.ImageBlock, error) {
+	data, err := readAttachment(path, MaxImageSize)
+	if err != nil {
+		return nil, err
+	}
+	return NewImage(data)
+}
+
+// NewDocument creates a document block from raw data. PDFs are sent as
+// base64 and UTF-8 text as plain text; other formats are rejected. An empty
+// title is omitted.
+func NewDocument(data []byte, title string) (*types.DocumentBlock, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("document is empty")
+	}
+	if len(data) > MaxDocumentSize {
+		return nil, fmt.Errorf("document is %d bytes, larger than the %d byte limit", len(data), MaxDocumentSize)
+	}
+
+	block := &types.DocumentBlock{}
+	if title != "" {
+		block.Title = &title
+	}
+
+	switch mediaType := detectMediaType(data); {
+	case mediaType == "application/pdf":
+		block.Source = types.ContentSource{
+			Type:      "base64",
+			MediaType: mediaType,
+			Data:      base64.StdEncoding.EncodeToString(data),
+		}
+	case strings.HasPrefix(mediaType, "text/plain") && utf8.Valid(data):
+		block.Source = types.ContentSource{
+			Type:      "text",
+			MediaType: "text/plain",
+			Data:      string(data),
+		}
+	default:
+		return nil, fmt.Errorf("unsupported document type %s", mediaType)
+	}
+
+	return block, nil
+}
+
+// NewDocumentFile reads a PDF or text file and creates a document block
+// titled with the file's base name.
+func NewDocumentFile(path string) (*types.DocumentBlock, error) {
+	data, err := readAttachment(path, MaxDocumentSize)
+	if err != nil {
+		return nil, err
+	}
+	return NewDocument(data, filepath.Base(path))
+}
+
+// readAttachment reads a file after checking it against limit.
+func readAttachment(path string, limit int64) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", path)
+	}
+	if info.Size() > limit {
+		return nil, fmt.Errorf("%s is %d bytes, larger than the %d byte limit", path, info.Size(), limit)
+	}
+	return os.ReadFile(path)
+}
+
+// detectMediaType sniffs the media type of data without parameters, except
+// for text types where the charset is kept.
+func detectMediaType(data []byte) string {
+	mediaType := http.DetectContentType(data)
+	if strings.HasPrefix(mediaType, "text/") {
+		return mediaType
+	}
+	if i := strings.Index(mediaType, ";"); i >= 0 {
+		mediaType = mediaType[:i]
+	}
+	return mediaType
+}
@@ -2,6 +2,11 @@ package protocol
 
 import (
 	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/nabkey/claude-agent-sdk-go/errors"
 	"github.com/nabkey/claude-agent-sdk-go/types"
@@ -26,12 +31,15 @@ func ParseMessage(data map[string]any) (types.Message, error) {
 	case "stream_event":
 		return parseStreamEvent(data)
 	default:
-		return nil, errors.NewMessageParseError("Unknown message type: "+msgType, data)
+		return &types.UnknownMessage{Type: msgType, Raw: data}, nil
 	}
 }
 
 func parseUserMessage(data map[string]any) (*types.UserMessage, error) {
-	msg := &types.UserMessage{}
+	msg := &types.UserMessage{
+		UUID:      getString(data, "uuid"),
+		SessionID: getString(data, "session_id"),
+	}
 
 	if parentID, ok := data["parent_tool_use_id"].(string); ok {
 		msg.ParentToolUseID = &parentID
@@ -62,7 +70,10 @@ func parseUserMessage(data map[string]any) (*types.UserMessage, error) {
 }
 
 func parseAssistantMessage(data map[string]any) (*types.AssistantMessage, error) {
-	msg := &types.AssistantMessage{}
+	msg := &types.AssistantMessage{
+		UUID:      getString(data, "uuid"),
+		SessionID: getString(data, "session_id"),
+	}
 
 	if parentID, ok := data["parent_tool_use_id"].(string); ok {
 		msg.ParentToolUseID = &parentID
@@ -74,6 +85,21 @@ func parseAssistantMessage(data map[string]any) (*types.AssistantMessage, error)
 	}
 
 	msg.Model, _ = messageData["model"].(string)
+	msg.ID = getString(messageData, "id")
+	if stopReason, ok := messageData["stop_reason"].(string); ok {
+		msg.StopReason = &stopReason
+	}
+	if stopSequence, ok := messageData["stop_sequence"].(string); ok {
+		msg.StopSequence = &stopSequence
+	}
+	if usage := getMap(messageData, "usage"); usage != nil {
+		msg.Usage = parseUsage(usage)
+	}
+
+	if errType, ok := data["error"].(string); ok {
+		e := types.AssistantMessageError(errType)
+		msg.Error = &e
+	}
 
 	contentRaw, ok := messageData["content"].([]any)
 	if !ok {
@@ -89,6 +115,39 @@ func parseAssistantMessage(data map[string]any) (*types.AssistantMessage, error)
 	return msg, nil
 }
 
+// retryAfterPattern finds retry hints such as "retry after 30 seconds" in
+// API error text.
+var retryAfterPattern = regexp.MustCompile(`(?i)retry[- ]after:?\s*(\d+(?:\.\d+)?)\s*(ms|milliseconds?|s|seconds?)?`)
+
+// AssistantError converts the Error of an assistant message into a typed
+// error from the errors package, using the message text as the error
+// message. It returns nil when the message carries no error.
+func AssistantError(msg *types.AssistantMessage) error {
+	if msg == nil || msg.Error == nil {
+		return nil
+	}
+
+	var texts []string
+	for _, block := range msg.Content {
+		if text, ok := block.(*types.TextBlock); ok && text.Text != "" {
+			texts = append(texts, text.Text)
+		}
+	}
+	message := strings.Join(texts, "\n")
+
+	var retryAfter time.Duration
+	if m := retryAfterPattern.FindStringSubmatch(message); m != nil {
+		value, _ := strconv.ParseFloat(m[1], 64)
+		unit := time.Second
+		if strings.HasPrefix(strings.ToLower(m[2]), "m") {
+			unit = time.Millisecond
+		}
+		retryAfter = time.Duration(value * float64(unit))
+	}
+
+	return errors.NewAPIError(string(*msg.Error), message, retryAfter)
+}
+
 func parseSystemMessage(data map[string]any) (*types.SystemMessage, error) {
 	msg := &types.SystemMessage{
 		Data: data,
@@ -100,9 +159,80 @@ func parseSystemMessage(data map[string]any) (*types.SystemMessage, error) {
 	}
 	msg.Subtype = subtype
 
+	switch subtype {
+	case types.SystemSubtypeInit:
+		msg.Init = parseSystemInit(data)
+	case types.SystemSubtypeCompactBoundary:
+		msg.CompactBoundary = parseCompactBoundary(data)
+	}
+
 	return msg, nil
 }
 
+func parseSystemInit(data map[string]any) *types.SystemInit {
+	init := &types.SystemInit{
+		SessionID:         getString(data, "session_id"),
+		CWD:               getString(data, "cwd"),
+		Model:             getString(data, "model"),
+		Tools:             getStringSlice(data, "tools"),
+		PermissionMode:    types.PermissionMode(getString(data, "permissionMode")),
+		SlashCommands:     getStringSlice(data, "slash_commands"),
+		APIKeySource:      getString(data, "apiKeySource"),
+		Agents:            getStringSlice(data, "agents"),
+		Skills:            getStringSlice(data, "skills"),
+		OutputStyle:       getString(data, "output_style"),
+		ClaudeCodeVersion: getString(data, "claude_code_version"),
+	}
+
+	if servers, ok := data["mcp_servers"].([]any); ok {
+		for _, s := range servers {
+			if m, ok := s.(map[string]any); ok {
+				init.MCPServers = append(init.MCPServers, types.MCPServerStatus{
+					Name:   getString(m, "name"),
+					Status: getString(m, "status"),
+				})
+			}
+		}
+	}
+
+	if plugins, ok := data["plugins"].([]any); ok {
+		for _, p := range plugins {
+			if m, ok := p.(map[string]any); ok {
+				init.Plugins = append(init.Plugins, types.InitPlugin{
+					Name: getString(m, "name"),
+					Path: getString(m, "path"),
+				})
+			}
+		}
+	}
+
+	return init
+}
+
+func parseCompactBoundary(data map[string]any) *types.CompactBoundary {
+	boundary := &types.CompactBoundary{}
+	if metadata := getMap(data, "compact_metadata"); metadata != nil {
+		boundary.Trigger = getString(metadata, "trigger")
+		boundary.PreTokens = getInt(metadata, "pre_tokens")
+	}
+	return boundary
+}
+
+// getStringSlice reads a JSON array of strings, skipping other elements.
+func getStringSlice(data map[string]any, key string) []string {
+	items, ok := data[key].([]any)
+	if !ok {
+		return nil
+	}
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
 func parseResultMessage(data map[string]any) (*types.ResultMessage, error) {
 	msg := &types.ResultMessage{}
 
@@ -123,7 +253,10 @@ func parseResultMessage(data map[string]any) (*types.ResultMessage, error) {
 		msg.TotalCostUSD = &cost
 	}
 	if usage, ok := data["usage"].(map[string]any); ok {
-		msg.Usage = usage
+		msg.Usage = parseUsage(usage)
+	}
+	if modelUsage, ok := data["modelUsage"].(map[string]any); ok {
+		msg.ModelUsage = parseModelUsage(modelUsage)
 	}
 	if result, ok := data["result"].(string); ok {
 		msg.Result = &result
@@ -133,6 +266,60 @@ func parseResultMessage(data map[string]any) (*types.ResultMessage, error) {
 	return msg, nil
 }
 
+// ParseUsage converts a usage object into a typed Usage.
+func ParseUsage(data map[string]any) *types.Usage {
+	return parseUsage(data)
+}
+
+// parseUsage converts a usage object into a typed Usage.
+func parseUsage(data map[string]any) *types.Usage {
+	usage := &types.Usage{
+		InputTokens:              getInt(data, "input_tokens"),
+		OutputTokens:             getInt(data, "output_tokens"),
+		CacheCreationInputTokens: getInt(data, "cache_creation_input_tokens"),
+		CacheReadInputTokens:     getInt(data, "cache_read_input_tokens"),
+		Raw:                      data,
+	}
+	usage.ServiceTier, _ = data["service_tier"].(string)
+	if stu, ok := data["server_tool_use"].(map[string]any); ok {
+		usage.ServerToolUse = &types.ServerToolUse{
+			WebSearchRequests: getInt(stu, "web_search_requests"),
+			WebFetchRequests:  getInt(stu, "web_fetch_requests"),
+		}
+	}
+	return usage
+}
+
+// parseModelUsage converts the per-model usage breakdown of a result.
+func parseModelUsage(data map[string]any) map[string]types.ModelUsage {
+	result := make(map[string]types.ModelUsage, len(data))
+	for model, v := range data {
+		m, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+		usage := types.ModelUsage{
+			InputTokens:              getInt(m, "inputTokens"),
+			OutputTokens:             getInt(m, "outputTokens"),
+			CacheReadInputTokens:     getInt(m, "cacheReadInputTokens"),
+			CacheCreationInputTokens: getInt(m, "cacheCreationInputTokens"),
+			WebSearchRequests:        getInt(m, "webSearchRequests"),
+			ContextWindow:            getInt(m, "contextWindow"),
+		}
+		usage.CostUSD, _ = m["costUSD"].(float64)
+		result[model] = usage
+	}
+	return result
+}
+
+// getInt reads a JSON number as an int.
+func getInt(data map[string]any, key string) int {
+	if v, ok := data[key].(float64); ok {
+		return int(v)
+	}
+	return 0
+}
+
 func parseStreamEvent(data map[string]any) (*types.StreamEvent, error) {
 	msg := &types.StreamEvent{}
 
@@ -150,6 +337,15 @@ func parseStreamEvent(data map[string]any) (*types.StreamEvent, error) {
 	return msg, nil
 }
 
+// ParseContentBlock parses a single content block map.
+func ParseContentBlock(data map[string]any) types.ContentBlock {
+	blocks, _ := parseContentBlocks([]any{data})
+	if len(blocks) == 0 {
+		return nil
+	}
+	return blocks[0]
+}
+
 func parseContentBlocks(rawBlocks []any) ([]types.ContentBlock, error) {
 	blocks := make([]types.ContentBlock, 0, len(rawBlocks))
 
@@ -164,7 +360,15 @@ func parseContentBlocks(rawBlocks []any) ([]types.ContentBlock, error) {
 		switch blockType {
 		case "text":
 			text, _ := blockData["text"].(string)
-			blocks = append(blocks, &types.TextBlock{Text: text})
+			block := &types.TextBlock{Text: text}
+			if citations, ok := blockData["citations"].([]any); ok {
+				for _, c := range citations {
+					if citation, ok := c.(map[string]any); ok {
+						block.Citations = append(block.Citations, citation)
+					}
+				}
+			}
+			blocks = append(blocks, block)
 
 		case "thinking":
 			blocks = append(blocks, &types.ThinkingBlock{
@@ -189,12 +393,172 @@ func parseContentBlocks(rawBlocks []any) ([]types.ContentBlock, error) {
 				block.IsError = &isErr
 			}
 			blocks = append(blocks, block)
+
+		case "redacted_thinking":
+			blocks = append(blocks, &types.RedactedThinkingBlock{Data: getString(blockData, "data")})
+
+		case "image":
+			blocks = append(blocks, &types.ImageBlock{Source: parseContentSource(getMap(blockData, "source"))})
+
+		case "document":
+			block := &types.DocumentBlock{Source: parseContentSource(getMap(blockData, "source"))}
+			if title, ok := blockData["title"].(string); ok {
+				block.Title = &title
+			}
+			if context, ok := blockData["context"].(string); ok {
+				block.Context = &context
+			}
+			blocks = append(blocks, block)
+
+		case "server_tool_use", "mcp_tool_use":
+			input, _ := blockData["input"].(map[string]any)
+			blocks = append(blocks, &types.ServerToolUseBlock{
+				ID:         getString(blockData, "id"),
+				Name:       getString(blockData, "name"),
+				Input:      input,
+				ServerName: getString(blockData, "server_name"),
+			})
+
+		case "web_search_tool_result":
+			blocks = append(blocks, parseWebSearchToolResult(blockData))
+
+		default:
+			if strings.HasSuffix(blockType, "_tool_result") {
+				blocks = append(blocks, &types.ServerToolResultBlock{
+					Type:      blockType,
+					ToolUseID: getString(blockData, "tool_use_id"),
+					Content:   blockData["content"],
+				})
+				continue
+			}
+			blocks = append(blocks, &types.UnknownBlock{Type: blockType, Raw: blockData})
 		}
 	}
 
 	return blocks, nil
 }
 
+func parseContentSource(data map[string]any) types.ContentSource {
+	return types.ContentSource{
+		Type:      getString(data, "type"),
+		MediaType: getString(data, "media_type"),
+		Data:      getString(data, "data"),
+		URL:       getString(data, "url"),
+		FileID:    getString(data, "file_id"),
+	}
+}
+
+func parseWebSearchToolResult(data map[string]any) *types.WebSearchToolResultBlock {
+	block := &types.WebSearchToolResultBlock{ToolUseID: getString(data, "tool_use_id")}
+
+	// Content is a list of results, or an error object when the search failed
+	switch content := data["content"].(type) {
+	case []any:
+		for _, item := range content {
+			m, ok := item.(map[string]any)
+			if !ok {
+				continue
+			}
+			result := types.WebSearchResult{
+				URL:              getString(m, "url"),
+				Title:            getString(m, "title"),
+				EncryptedContent: getString(m, "encrypted_content"),
+			}
+			if age, ok := m["page_age"].(string); ok {
+				result.PageAge = &age
+			}
+			block.Results = append(block.Results, result)
+		}
+	case map[string]any:
+		block.ErrorCode = getString(content, "error_code")
+	}
+
+	return block
+}
+
+// MarshalContentBlocks converts content blocks into their wire format for a
+// user message. Only blocks a user message may carry are accepted: text,
+// image, document and tool_result.
+func MarshalContentBlocks(blocks []types.ContentBlock) ([]map[string]any, error) {
+	result := make([]map[string]any, 0, len(blocks))
+	for _, block := range blocks {
+		switch b := block.(type) {
+		case *types.TextBlock:
+			result = append(result, map[string]any{"type": "text", "text": b.Text})
+
+		case *types.ImageBlock:
+			result = append(result, map[string]any{"type": "image", "source": marshalContentSource(b.Source)})
+
+		case *types.DocumentBlock:
+			m := map[string]any{"type": "document", "source": marshalContentSource(b.Source)}
+			if b.Title != nil {
+				m["title"] = *b.Title
+			}
+			if b.Context != nil {
+				m["context"] = *b.Context
+			}
+			result = append(result, m)
+
+		case *types.ToolResultBlock:
+			m := map[string]any{"type": "tool_result", "tool_use_id": b.ToolUseID}
+			if b.Content != nil {
+				content, err := marshalToolResultContent(b.Content)
+				if err != nil {
+					return nil, err
+				}
+				m["content"] = content
+			}
+			if b.IsError != nil {
+				m["is_error"] = *b.IsError
+			}
+			result = append(result, m)
+
+		default:
+			return nil, fmt.Errorf("content block %T cannot be sent in a user message", block)
+		}
+	}
+	return result, nil
+}
+
+// marshalToolResultContent converts the content of a tool result. Content
+// blocks are marshalled like those of the message itself; strings and
+// content already in wire format are passed through.
+func marshalToolResultContent(content any) (any, error) {
+	var blocks []types.ContentBlock
+	switch c := content.(type) {
+	case []types.ContentBlock:
+		blocks = c
+	case types.ContentBlock:
+		blocks = []types.ContentBlock{c}
+	default:
+		return content, nil
+	}
+
+	for _, block := range blocks {
+		if _, ok := block.(*types.ToolResultBlock); ok {
+			return nil, fmt.Errorf("tool_result content cannot contain another tool_result")
+		}
+	}
+	return MarshalContentBlocks(blocks)
+}
+
+func marshalContentSource(source types.ContentSource) map[string]any {
+	m := map[string]any{"type": source.Type}
+	if source.MediaType != "" {
+		m["media_type"] = source.MediaType
+	}
+	if source.Data != "" {
+		m["data"] = source.Data
+	}
+	if source.URL != "" {
+		m["url"] = source.URL
+	}
+	if source.FileID != "" {
+		m["file_id"] = source.FileID
+	}
+	return m
+}
+
 // MarshalUserInput creates a user input message for streaming mode.
 func MarshalUserInput(prompt string, sessionID string) ([]byte, error) {
 	msg := types.UserInputMessage{
@@ -7,6 +7,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -24,20 +25,31 @@ type Query struct {
 	isStreamingMode   bool
 	canUseTool        CanUseToolCallback
 	hooks             map[types.HookEvent][]HookMatcherInternal
+	onHookTimeout     func(types.HookTimeoutEvent)
+	onAsyncHookResult func(types.AsyncHookResult)
 	sdkMCPServers     map[string]*MCPServerHandler
 	initializeTimeout time.Duration
 
 	// Control protocol state
 	pendingResponses map[string]chan *ControlResult
-	hookCallbacks    map[string]types.HookCallback
+	streamErr        error // set once no more control responses can arrive
+	hookCallbacks    map[string]registeredHook
 	nextCallbackID   int64
 	requestCounter   int64
 	pendingMu        sync.Mutex
 	hookMu           sync.Mutex
 
+	// Background hook work started through HookContext.Go. asyncMu also
+	// guards starting work against closing.
+	asyncWG      sync.WaitGroup
+	asyncMu      sync.Mutex
+	asyncOutputs []*types.HookOutput
+
 	// Message stream
 	messageChan        chan map[string]any
 	errorChan          chan error
+	errMu              sync.Mutex
+	err                error
 	initialized        bool
 	closed             atomic.Bool
 	initResult         map[string]any
@@ -64,6 +76,26 @@ type HookMatcherInternal struct {
 	Timeout     *float64
 }
 
+// registeredHook is a hook callback together with the settings of its matcher.
+type registeredHook struct {
+	event           types.HookEvent
+	callback        types.HookCallback
+	timeout         time.Duration
+	timeoutBehavior types.HookTimeoutBehavior
+}
+
+// defaultHookTimeout matches the CLI's default hook timeout.
+const defaultHookTimeout = 60 * time.Second
+
+// asyncHookCloseTimeout bounds how long Close waits for background hook
+// work to return after cancelling its context.
+const asyncHookCloseTimeout = 5 * time.Second
+
+// hookTimeoutGrace pads the timeout sent to the CLI beyond the deadline the
+// SDK enforces, so that the output of an overrunning callback, such as a
+// fail-closed deny, reaches the CLI before it stops waiting.
+const hookTimeoutGrace = 5 * time.Second
+
 // ControlResult holds the result of a control request.
 type ControlResult struct {
 	Response map[string]any
@@ -94,6 +126,10 @@ type QueryOptions struct {
 	Hooks             map[types.HookEvent][]types.HookMatcher
 	SDKMCPServers     map[string]*MCPServerHandler
 	InitializeTimeout time.Duration
+	// OnHookTimeout is called when a hook callback overruns its timeout.
+	OnHookTimeout func(types.HookTimeoutEvent)
+	// OnAsyncHookResult is called when background hook work completes.
+	OnAsyncHookResult func(types.AsyncHookResult)
 }
 
 // NewQuery creates a new Query instance.
@@ -111,7 +147,9 @@ func NewQuery(opts *QueryOptions) *Query {
 		sdkMCPServers:      opts.SDKMCPServers,
 		initializeTimeout:  opts.InitializeTimeout,
 		pendingResponses:   make(map[string]chan *ControlResult),
-		hookCallbacks:      make(map[string]types.HookCallback),
+		hookCallbacks:      make(map[string]registeredHook),
+		onHookTimeout:      opts.OnHookTimeout,
+		onAsyncHookResult:  opts.OnAsyncHookResult,
 		messageChan:        make(chan map[string]any, 100),
 		errorChan:          make(chan error, 1),
 		firstResultEvent:   make(chan struct{}),
@@ -126,16 +164,27 @@ func NewQuery(opts *QueryOptions) *Query {
 		for event, matchers := range opts.Hooks {
 			q.hooks[event] = make([]HookMatcherInternal, 0, len(matchers))
 			for _, m := range matchers {
+				// A zero deadline would fail every callback at once
+				timeout := defaultHookTimeout
+				if m.Timeout != nil && *m.Timeout > 0 {
+					timeout = time.Duration(*m.Timeout * float64(time.Second))
+				}
+				cliTimeout := (timeout + hookTimeoutGrace).Seconds()
 				callbackIDs := make([]string, 0, len(m.Hooks))
 				for _, callback := range m.Hooks {
 					callbackID := fmt.Sprintf("hook_%d", atomic.AddInt64(&q.nextCallbackID, 1)-1)
-					q.hookCallbacks[callbackID] = callback
+					q.hookCallbacks[callbackID] = registeredHook{
+						event:           event,
+						callback:        callback,
+						timeout:         timeout,
+						timeoutBehavior: m.TimeoutBehavior,
+					}
 					callbackIDs = append(callbackIDs, callbackID)
 				}
 				q.hooks[event] = append(q.hooks[event], HookMatcherInternal{
 					Matcher:     m.Matcher,
 					CallbackIDs: callbackIDs,
-					Timeout:     m.Timeout,
+					Timeout:     &cliTimeout,
 				})
 			}
 		}
@@ -197,9 +246,44 @@ func (q *Query) Initialize(ctx context.Context) (map[string]any, error) {
 	return response, nil
 }
 
+// fail records the error that ended the message stream.
+func (q *Query) fail(err error) {
+	q.errMu.Lock()
+	if q.err == nil {
+		q.err = err
+	}
+	q.errMu.Unlock()
+
+	select {
+	case q.errorChan <- err:
+	default:
+	}
+}
+
+// endStream fails pending and future control requests once the message
+// stream has ended, as their responses can no longer arrive.
+func (q *Query) endStream() {
+	err := q.Err()
+	if err == nil {
+		err = errors.NewCLIConnectionError("CLI message stream ended", nil)
+	}
+
+	q.pendingMu.Lock()
+	defer q.pendingMu.Unlock()
+
+	q.streamErr = err
+	for _, ch := range q.pendingResponses {
+		select {
+		case ch <- &ControlResult{Error: err}:
+		default:
+		}
+	}
+}
+
 // readMessages reads messages from transport and routes them.
 func (q *Query) readMessages(ctx context.Context) {
 	defer close(q.messageChan)
+	defer q.endStream()
 
 	msgChan, errChan := q.transport.ReadMessages(ctx)
 
@@ -211,24 +295,15 @@ func (q *Query) readMessages(ctx context.Context) {
 			return
 		case err, ok := <-errChan:
 			if ok && err != nil {
-				// Signal pending control requests
-				q.pendingMu.Lock()
-				for _, ch := range q.pendingResponses {
-					select {
-					case ch <- &ControlResult{Error: err}:
-					default:
-					}
-				}
-				q.pendingMu.Unlock()
-
-				select {
-				case q.errorChan <- err:
-				default:
-				}
+				q.fail(err)
 			}
 			return
 		case msg, ok := <-msgChan:
 			if !ok {
+				// The transport reports its error before closing the stream
+				if err, ok := <-errChan; ok && err != nil {
+					q.fail(err)
+				}
 				return
 			}
 
@@ -418,7 +493,7 @@ func (q *Query) handleHookCallback(ctx context.Context, request map[string]any)
 	toolUseID, _ := request["tool_use_id"].(*string)
 
 	q.hookMu.Lock()
-	callback, exists := q.hookCallbacks[callbackID]
+	hook, exists := q.hookCallbacks[callbackID]
 	q.hookMu.Unlock()
 
 	if !exists {
@@ -431,14 +506,258 @@ func (q *Query) handleHookCallback(ctx context.Context, request map[string]any)
 		return nil, err
 	}
 
-	hookCtx := &types.HookContext{Signal: nil}
-	output, err := callback(ctx, hookInput, toolUseID, hookCtx)
-	if err != nil {
-		return nil, err
+	// Run the callback under the matcher's deadline. The result channel is
+	// buffered so an overrunning callback can still finish without blocking.
+	hookCtx := &types.HookContext{
+		Signal: nil,
+		Go: func(fn types.AsyncHookFunc) {
+			q.runAsyncHook(hook, callbackID, toolUseID, fn)
+		},
+	}
+	callCtx, cancel := context.WithTimeout(ctx, hook.timeout)
+	defer cancel()
+
+	type hookResult struct {
+		output *types.HookOutput
+		err    error
 	}
+	resultChan := make(chan hookResult, 1)
+	go func() {
+		output, err := hook.callback(callCtx, hookInput, toolUseID, hookCtx)
+		resultChan <- hookResult{output: output, err: err}
+	}()
 
-	// Convert output to map
-	return hookOutputToMap(output), nil
+	select {
+	case result := <-resultChan:
+		if result.err != nil {
+			return nil, result.err
+		}
+		// Convert output to map
+		return hookOutputToMap(q.withAsyncOutputs(hook.event, result.output)), nil
+
+	case <-callCtx.Done():
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		output := hookTimeoutOutput(hook, callbackID)
+		if q.onHookTimeout != nil {
+			behavior := hook.timeoutBehavior
+			if behavior == "" {
+				behavior = types.HookTimeoutFailOpen
+			}
+			q.onHookTimeout(types.HookTimeoutEvent{
+				Event:      hook.event,
+				CallbackID: callbackID,
+				ToolUseID:  toolUseID,
+				Timeout:    hook.timeout,
+				Behavior:   behavior,
+				Output:     output,
+			})
+		}
+		return hookOutputToMap(output), nil
+	}
+}
+
+// runAsyncHook runs background hook work until it returns or the query is
+// closed, queueing its output for delivery with the next hook response.
+func (q *Query) runAsyncHook(hook registeredHook, callbackID string, toolUseID *string, fn types.AsyncHookFunc) {
+	// Adding under asyncMu keeps Add from racing with the Wait in Close
+	q.asyncMu.Lock()
+	if q.closed.Load() {
+		q.asyncMu.Unlock()
+		return
+	}
+	q.asyncWG.Add(1)
+	q.asyncMu.Unlock()
+
+	go func() {
+		output, err := func() (*types.HookOutput, error) {
+			defer q.asyncWG.Done()
+			return fn(q.ctx)
+		}()
+
+		// The result callback is not waited for by Close, so it may call
+		// back into the client, for example to send a follow-up query
+		if err == nil && output != nil && q.ctx.Err() == nil {
+			q.asyncMu.Lock()
+			q.asyncOutputs = append(q.asyncOutputs, output)
+			q.asyncMu.Unlock()
+		}
+		if q.onAsyncHookResult != nil {
+			q.onAsyncHookResult(types.AsyncHookResult{
+				Event:      hook.event,
+				CallbackID: callbackID,
+				ToolUseID:  toolUseID,
+				Output:     output,
+				Err:        err,
+			})
+		}
+	}()
+}
+
+// withAsyncOutputs folds the system messages and additional context of
+// completed background hook work into output. Outputs that defer to
+// background work themselves are left untouched.
+func (q *Query) withAsyncOutputs(event types.HookEvent, output *types.HookOutput) *types.HookOutput {
+	if output != nil && output.Async != nil && *output.Async {
+		return output
+	}
+
+	q.asyncMu.Lock()
+	pending := q.asyncOutputs
+	q.asyncOutputs = nil
+	q.asyncMu.Unlock()
+
+	if len(pending) == 0 {
+		return output
+	}
+
+	merged := &types.HookOutput{}
+	if output != nil {
+		*merged = *output
+	}
+
+	var messages, contexts []string
+	if merged.SystemMessage != nil && *merged.SystemMessage != "" {
+		messages = append(messages, *merged.SystemMessage)
+	}
+	for _, p := range pending {
+		if p.SystemMessage != nil && *p.SystemMessage != "" {
+			messages = append(messages, *p.SystemMessage)
+		}
+		if c := additionalContext(p.HookSpecificOutput); c != "" {
+			contexts = append(contexts, c)
+		}
+	}
+
+	// Additional context can only be attached to events that accept it;
+	// elsewhere it is passed on as a system message.
+	if len(contexts) > 0 && !appendAdditionalContext(event, merged, strings.Join(contexts, "\n")) {
+		messages = append(messages, contexts...)
+	}
+	if len(messages) > 0 {
+		message := strings.Join(messages, "\n")
+		merged.SystemMessage = &message
+	}
+	return merged
+}
+
+// additionalContext returns the additional context carried by a hook-specific output.
+func additionalContext(hso types.HookSpecificOutput) string {
+	var c *string
+	switch v := hso.(type) {
+	case *types.PostToolUseHookSpecificOutput:
+		c = v.AdditionalContext
+	case *types.UserPromptSubmitHookSpecificOutput:
+		c = v.AdditionalContext
+	case *types.SessionStartHookSpecificOutput:
+		c = v.AdditionalContext
+	case *types.SubagentStartHookSpecificOutput:
+		c = v.AdditionalContext
+	case *types.NotificationHookSpecificOutput:
+		c = v.AdditionalContext
+	}
+	if c == nil {
+		return ""
+	}
+	return *c
+}
+
+// appendAdditionalContext appends text to the additional context of output,
+// creating the hook-specific output if needed. It reports false when the
+// event does not accept additional context.
+func appendAdditionalContext(event types.HookEvent, output *types.HookOutput, text string) bool {
+	join := func(existing *string) *string {
+		if existing == nil || *existing == "" {
+			return &text
+		}
+		joined := *existing + "\n" + text
+		return &joined
+	}
+
+	switch hso := output.HookSpecificOutput.(type) {
+	case nil:
+	case *types.PostToolUseHookSpecificOutput:
+		c := *hso
+		c.AdditionalContext = join(c.AdditionalContext)
+		output.HookSpecificOutput = &c
+		return true
+	case *types.UserPromptSubmitHookSpecificOutput:
+		c := *hso
+		c.AdditionalContext = join(c.AdditionalContext)
+		output.HookSpecificOutput = &c
+		return true
+	case *types.SessionStartHookSpecificOutput:
+		c := *hso
+		c.AdditionalContext = join(c.AdditionalContext)
+		output.HookSpecificOutput = &c
+		return true
+	case *types.SubagentStartHookSpecificOutput:
+		c := *hso
+		c.AdditionalContext = join(c.AdditionalContext)
+		output.HookSpecificOutput = &c
+		return true
+	case *types.NotificationHookSpecificOutput:
+		c := *hso
+		c.AdditionalContext = join(c.AdditionalContext)
+		output.HookSpecificOutput = &c
+		return true
+	default:
+		return false
+	}
+
+	name := string(event)
+	switch event {
+	case types.HookEventPostToolUse:
+		output.HookSpecificOutput = &types.PostToolUseHookSpecificOutput{HookEventName: name, AdditionalContext: &text}
+	case types.HookEventUserPromptSubmit:
+		output.HookSpecificOutput = &types.UserPromptSubmitHookSpecificOutput{HookEventName: name, AdditionalContext: &text}
+	case types.HookEventSessionStart:
+		output.HookSpecificOutput = &types.SessionStartHookSpecificOutput{HookEventName: name, AdditionalContext: &text}
+	case types.HookEventSubagentStart:
+		output.HookSpecificOutput = &types.SubagentStartHookSpecificOutput{HookEventName: name, AdditionalContext: &text}
+	case types.HookEventNotification:
+		output.HookSpecificOutput = &types.NotificationHookSpecificOutput{HookEventName: name, AdditionalContext: &text}
+	default:
+		return false
+	}
+	return true
+}
+
+// hookTimeoutOutput builds the output sent in place of an overrunning callback's result.
+func hookTimeoutOutput(hook registeredHook, callbackID string) *types.HookOutput {
+	if hook.timeoutBehavior != types.HookTimeoutFailClosed {
+		return &types.HookOutput{}
+	}
+
+	reason := fmt.Sprintf("Hook %s timed out after %s", callbackID, hook.timeout)
+	switch hook.event {
+	case types.HookEventPreToolUse:
+		deny := string(types.PermissionBehaviorDeny)
+		return &types.HookOutput{
+			HookSpecificOutput: &types.PreToolUseHookSpecificOutput{
+				HookEventName:            string(hook.event),
+				PermissionDecision:       &deny,
+				PermissionDecisionReason: &reason,
+			},
+		}
+	case types.HookEventPermissionRequest:
+		return &types.HookOutput{
+			HookSpecificOutput: &types.PermissionRequestHookSpecificOutput{
+				HookEventName: string(hook.event),
+				Decision: &types.PermissionRequestDecision{
+					Behavior: types.PermissionBehaviorDeny,
+					Message:  reason,
+				},
+			},
+		}
+	case types.HookEventUserPromptSubmit, types.HookEventPostToolUse:
+		block := "block"
+		return &types.HookOutput{Decision: &block, Reason: &reason}
+	default:
+		cont := false
+		return &types.HookOutput{Continue: &cont, StopReason: &reason}
+	}
 }
 
 // handleMCPMessage handles MCP server requests.
@@ -479,6 +798,10 @@ func (q *Query) sendControlRequest(ctx context.Context, request map[string]any)
 	// Create response channel
 	respChan := make(chan *ControlResult, 1)
 	q.pendingMu.Lock()
+	if q.streamErr != nil {
+		q.pendingMu.Unlock()
+		return nil, q.streamErr
+	}
 	q.pendingResponses[requestID] = respChan
 	q.pendingMu.Unlock()
 
@@ -555,11 +878,35 @@ func (q *Query) ErrorChan() <-chan error {
 	return q.errorChan
 }
 
+// Err returns the error that ended the message stream, or nil if the
+// stream is still open or ended cleanly.
+func (q *Query) Err() error {
+	q.errMu.Lock()
+	defer q.errMu.Unlock()
+	return q.err
+}
+
 // Close closes the query and transport.
 func (q *Query) Close() error {
+	q.asyncMu.Lock()
 	q.closed.Store(true)
+	q.asyncMu.Unlock()
+
 	q.cancel()
-	return q.transport.Close()
+	err := q.transport.Close()
+
+	// Background hook work observes the cancelled context; work that
+	// ignores it is abandoned after asyncHookCloseTimeout
+	done := make(chan struct{})
+	go func() {
+		q.asyncWG.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(asyncHookCloseTimeout):
+	}
+	return err
 }
 
 // GetInitResult returns the initialization result.
@@ -733,9 +1080,107 @@ func parseHookInput(input any) (types.HookInput, error) {
 		}
 		return input, nil
 
+	case "Notification":
+		input := &types.NotificationHookInput{
+			BaseHookInput:    base,
+			HookEventName:    types.HookEventNotification,
+			Message:          getString(data, "message"),
+			NotificationType: getString(data, "notification_type"),
+		}
+		if title, ok := data["title"].(string); ok {
+			input.Title = &title
+		}
+		return input, nil
+
+	case "SessionStart":
+		return &types.SessionStartHookInput{
+			BaseHookInput: base,
+			HookEventName: types.HookEventSessionStart,
+			Source:        getString(data, "source"),
+			Model:         getString(data, "model"),
+		}, nil
+
+	case "SessionEnd":
+		return &types.SessionEndHookInput{
+			BaseHookInput: base,
+			HookEventName: types.HookEventSessionEnd,
+			Reason:        getString(data, "reason"),
+		}, nil
+
+	case "SubagentStart":
+		return &types.SubagentStartHookInput{
+			BaseHookInput: base,
+			HookEventName: types.HookEventSubagentStart,
+			AgentID:       getString(data, "agent_id"),
+			AgentType:     getString(data, "agent_type"),
+		}, nil
+
+	case "PermissionRequest":
+		suggestions, _ := data["permission_suggestions"].([]any)
+		return &types.PermissionRequestHookInput{
+			BaseHookInput:         base,
+			HookEventName:         types.HookEventPermissionRequest,
+			ToolName:              getString(data, "tool_name"),
+			ToolInput:             getMap(data, "tool_input"),
+			PermissionSuggestions: parsePermissionUpdates(suggestions),
+		}, nil
+
 	default:
-		return nil, fmt.Errorf("unknown hook event: %s", eventName)
+		// Unknown events are passed through so newer CLI versions keep working
+		return &types.GenericHookInput{
+			BaseHookInput: base,
+			HookEventName: types.HookEvent(eventName),
+			Data:          data,
+		}, nil
+	}
+}
+
+// parsePermissionUpdates converts raw permission updates to typed values.
+func parsePermissionUpdates(raw []any) []types.PermissionUpdate {
+	var updates []types.PermissionUpdate
+	for _, item := range raw {
+		data, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		update := types.PermissionUpdate{
+			Type: types.PermissionUpdateType(getString(data, "type")),
+		}
+		if rules, ok := data["rules"].([]any); ok {
+			for _, r := range rules {
+				ruleData, ok := r.(map[string]any)
+				if !ok {
+					continue
+				}
+				rule := types.PermissionRuleValue{ToolName: getString(ruleData, "toolName")}
+				if content, ok := ruleData["ruleContent"].(string); ok {
+					rule.RuleContent = &content
+				}
+				update.Rules = append(update.Rules, rule)
+			}
+		}
+		if behavior, ok := data["behavior"].(string); ok {
+			b := types.PermissionBehavior(behavior)
+			update.Behavior = &b
+		}
+		if mode, ok := data["mode"].(string); ok {
+			m := types.PermissionMode(mode)
+			update.Mode = &m
+		}
+		if dirs, ok := data["directories"].([]any); ok {
+			for _, d := range dirs {
+				if dir, ok := d.(string); ok {
+					update.Directories = append(update.Directories, dir)
+				}
+			}
+		}
+		if dest, ok := data["destination"].(string); ok {
+			d := types.PermissionUpdateDestination(dest)
+			update.Destination = &d
+		}
+		updates = append(updates, update)
 	}
+	return updates
 }
 
 // hookOutputToMap converts HookOutput to a map for JSON serialization.
@@ -799,6 +1244,59 @@ func hookOutputToMap(output *types.HookOutput) map[string]any {
 				hsoMap["additionalContext"] = *hso.AdditionalContext
 			}
 			result["hookSpecificOutput"] = hsoMap
+
+		case *types.SessionStartHookSpecificOutput:
+			hsoMap := map[string]any{"hookEventName": hso.HookEventName}
+			if hso.AdditionalContext != nil {
+				hsoMap["additionalContext"] = *hso.AdditionalContext
+			}
+			result["hookSpecificOutput"] = hsoMap
+
+		case *types.SubagentStartHookSpecificOutput:
+			hsoMap := map[string]any{"hookEventName": hso.HookEventName}
+			if hso.AdditionalContext != nil {
+				hsoMap["additionalContext"] = *hso.AdditionalContext
+			}
+			result["hookSpecificOutput"] = hsoMap
+
+		case *types.NotificationHookSpecificOutput:
+			hsoMap := map[string]any{"hookEventName": hso.HookEventName}
+			if hso.AdditionalContext != nil {
+				hsoMap["additionalContext"] = *hso.AdditionalContext
+			}
+			result["hookSpecificOutput"] = hsoMap
+
+		case *types.PermissionRequestHookSpecificOutput:
+			hsoMap := map[string]any{"hookEventName": hso.HookEventName}
+			if d := hso.Decision; d != nil {
+				decision := map[string]any{"behavior": string(d.Behavior)}
+				if d.UpdatedInput != nil {
+					decision["updatedInput"] = d.UpdatedInput
+				}
+				if d.UpdatedPermissions != nil {
+					perms := make([]map[string]any, len(d.UpdatedPermissions))
+					for i, p := range d.UpdatedPermissions {
+						perms[i] = p.ToMap()
+					}
+					decision["updatedPermissions"] = perms
+				}
+				if d.Message != "" {
+					decision["message"] = d.Message
+				}
+				if d.Interrupt {
+					decision["interrupt"] = true
+				}
+				hsoMap["decision"] = decision
+			}
+			result["hookSpecificOutput"] = hsoMap
+
+		case *types.GenericHookSpecificOutput:
+			hsoMap := make(map[string]any, len(hso.Fields)+1)
+			for k, v := range hso.Fields {
+				hsoMap[k] = v
+			}
+			hsoMap["hookEventName"] = hso.HookEventName
+			result["hookSpecificOutput"] = hsoMap
 		}
 	}
 
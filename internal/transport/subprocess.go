@@ -13,6 +13,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/nabkey/claude-agent-sdk-go/errors"
 	"github.com/nabkey/claude-agent-sdk-go/types"
@@ -21,6 +22,8 @@ import (
 const (
 	defaultMaxBufferSize     = 1024 * 1024 // 1MB
 	minimumClaudeCodeVersion = "2.0.0"
+	stderrTailLines          = 20          // stderr lines kept for ProcessError
+	stderrDrainTimeout       = time.Second // wait for stderr before reporting an exit
 )
 
 // SubprocessTransport implements Transport using the Claude CLI as a subprocess.
@@ -40,6 +43,15 @@ type SubprocessTransport struct {
 	writeMu       sync.Mutex
 	closeMu       sync.Mutex
 	closed        bool
+
+	// Last lines written to stderr, reported with process failures
+	stderrMu   sync.Mutex
+	stderrTail []string
+	stderrDone chan struct{}
+
+	// The process is waited for once, by the reader or by Close
+	waitOnce sync.Once
+	waitErr  error
 }
 
 // SubprocessOptions contains configuration for the subprocess transport.
@@ -469,7 +481,8 @@ func (t *SubprocessTransport) Connect(ctx context.Context) error {
 	}
 
 	// Handle stderr in background
-	go t.handleStderr()
+	t.stderrDone = make(chan struct{})
+	go t.handleStderr(t.stderr, t.stderrDone)
 
 	// For non-streaming mode, close stdin immediately
 	if !t.isStreaming {
@@ -481,24 +494,45 @@ func (t *SubprocessTransport) Connect(ctx context.Context) error {
 	return nil
 }
 
-// handleStderr reads stderr and invokes callbacks.
-func (t *SubprocessTransport) handleStderr() {
-	if t.stderr == nil {
-		return
-	}
+// handleStderr reads stderr, keeps its tail and invokes callbacks.
+func (t *SubprocessTransport) handleStderr(stderr io.Reader, done chan struct{}) {
+	defer close(done)
 
-	scanner := bufio.NewScanner(t.stderr)
+	scanner := bufio.NewScanner(stderr)
 	for scanner.Scan() {
 		line := scanner.Text()
 		if line == "" {
 			continue
 		}
+
+		t.stderrMu.Lock()
+		if len(t.stderrTail) == stderrTailLines {
+			t.stderrTail = append(t.stderrTail[:0], t.stderrTail[1:]...)
+		}
+		t.stderrTail = append(t.stderrTail, line)
+		t.stderrMu.Unlock()
+
 		if t.options.Stderr != nil {
 			t.options.Stderr(line)
 		}
 	}
 }
 
+// StderrTail returns the last lines the process wrote to stderr, waiting
+// briefly for output still in flight.
+func (t *SubprocessTransport) StderrTail() string {
+	if t.stderrDone != nil {
+		select {
+		case <-t.stderrDone:
+		case <-time.After(stderrDrainTimeout):
+		}
+	}
+
+	t.stderrMu.Lock()
+	defer t.stderrMu.Unlock()
+	return strings.Join(t.stderrTail, "\n")
+}
+
 // Write sends data to the subprocess stdin.
 func (t *SubprocessTransport) Write(ctx context.Context, data string) error {
 	t.writeMu.Lock()
@@ -532,7 +566,7 @@ func (t *SubprocessTransport) Write(ctx context.Context, data string) error {
 // ReadMessages returns channels for messages and errors from stdout.
 func (t *SubprocessTransport) ReadMessages(ctx context.Context) (<-chan map[string]any, <-chan error) {
 	msgChan := make(chan map[string]any, 100)
-	errChan := make(chan error, 1)
+	errChan := make(chan error, 2)
 
 	go func() {
 		defer close(msgChan)
@@ -591,9 +625,11 @@ func (t *SubprocessTransport) ReadMessages(ctx context.Context) (<-chan map[stri
 			errChan <- errors.NewCLIConnectionError("Error reading stdout", err)
 		}
 
-		// Wait for process to complete
+		// Wait for process to complete. Stderr is drained first, as Wait
+		// closes the pipe.
 		if t.cmd != nil {
-			if err := t.cmd.Wait(); err != nil {
+			stderr := t.StderrTail()
+			if err := t.wait(); err != nil {
 				exitCode := -1
 				if t.cmd.ProcessState != nil {
 					exitCode = t.cmd.ProcessState.ExitCode()
@@ -602,7 +638,7 @@ func (t *SubprocessTransport) ReadMessages(ctx context.Context) (<-chan map[stri
 					errChan <- errors.NewProcessError(
 						fmt.Sprintf("Command failed with exit code %d", exitCode),
 						exitCode,
-						"",
+						stderr,
 					)
 				}
 			}
@@ -612,6 +648,15 @@ func (t *SubprocessTransport) ReadMessages(ctx context.Context) (<-chan map[stri
 	return msgChan, errChan
 }
 
+// wait waits for the process to exit. exec.Cmd.Wait must not be called
+// concurrently, so the reader and Close share a single call.
+func (t *SubprocessTransport) wait() error {
+	t.waitOnce.Do(func() {
+		t.waitErr = t.cmd.Wait()
+	})
+	return t.waitErr
+}
+
 // EndInput closes the stdin pipe.
 func (t *SubprocessTransport) EndInput() error {
 	t.writeMu.Lock()
@@ -630,11 +675,10 @@ func (t *SubprocessTransport) Close() error {
 	t.closeMu.Lock()
 	defer t.closeMu.Unlock()
 
-	t.closed = true
-	t.ready = false
-
 	// Close stdin
 	t.writeMu.Lock()
+	t.closed = true
+	t.ready = false
 	if t.stdin != nil {
 		_ = t.stdin.Close()
 		t.stdin = nil
@@ -650,7 +694,7 @@ func (t *SubprocessTransport) Close() error {
 	// Terminate process
 	if t.cmd != nil && t.cmd.Process != nil {
 		_ = t.cmd.Process.Kill()
-		_ = t.cmd.Wait()
+		_ = t.wait()
 	}
 
 	t.stdout = nil
@@ -664,8 +708,30 @@ func (t *SubprocessTransport) IsReady() bool {
 	return t.ready && !t.closed
 }
 
+// versionChecks records the version check of each CLI binary, so that
+// processes started in quick succession run "claude -v" only once.
+var versionChecks sync.Map // versionCheckKey -> *sync.Once
+
+// versionCheckKey identifies a CLI binary; a changed file is checked again.
+type versionCheckKey struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
 // checkCLIVersion checks if the CLI version meets minimum requirements.
 func (t *SubprocessTransport) checkCLIVersion(ctx context.Context) {
+	key := versionCheckKey{path: t.cliPath}
+	if info, err := os.Stat(t.cliPath); err == nil {
+		key.size = info.Size()
+		key.modTime = info.ModTime()
+	}
+	once, _ := versionChecks.LoadOrStore(key, &sync.Once{})
+	once.(*sync.Once).Do(func() { t.runVersionCheck(ctx) })
+}
+
+// runVersionCheck runs "claude -v" and warns about unsupported versions.
+func (t *SubprocessTransport) runVersionCheck(ctx context.Context) {
 	ctx, cancel := context.WithTimeout(ctx, 2*1e9) // 2 second timeout
 	defer cancel()
 
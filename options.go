@@ -3,7 +3,9 @@ package claude
 
 import (
 	"context"
+	"math"
 
+	"github.com/nabkey/claude-agent-sdk-go/cost"
 	"github.com/nabkey/claude-agent-sdk-go/types"
 )
 
@@ -44,6 +46,10 @@ type AgentOptions struct {
 	// MaxBudgetUSD limits the maximum cost in USD.
 	MaxBudgetUSD *float64
 
+	// Budget is a spending limit shared with other queries and clients.
+	// When set, MaxBudgetUSD is capped at the budget's remaining amount.
+	Budget *cost.Budget
+
 	// Model specifies the Claude model to use.
 	Model *string
 
@@ -85,6 +91,23 @@ type AgentOptions struct {
 	// Hooks configures hook callbacks for various events.
 	Hooks map[types.HookEvent][]types.HookMatcher
 
+	// OnHookTimeout is called when a hook callback overruns its matcher's
+	// timeout and the SDK answers on its behalf.
+	OnHookTimeout func(types.HookTimeoutEvent)
+
+	// OnAsyncHookResult is called when background work started through
+	// HookContext.Go completes, including when it fails. It is the only
+	// guaranteed way to observe the work's output. It runs on its own
+	// goroutine and may call Client methods, including Close.
+	OnAsyncHookResult func(types.AsyncHookResult)
+
+	// Retry re-runs a Query that fails transiently. Nil disables retries.
+	Retry *RetryPolicy
+
+	// Reconnect makes a Client restart the CLI and resume the conversation
+	// when the process exits unexpectedly. Nil disables reconnecting.
+	Reconnect *ReconnectPolicy
+
 	// User sets the Unix user to run the CLI process as.
 	User *string
 
@@ -199,12 +222,42 @@ func (o *AgentOptions) WithHook(event types.HookEvent, matcher types.HookMatcher
 	return o
 }
 
+// WithHooks merges a hook configuration, such as one built with hooks.Build,
+// into the existing hooks.
+func (o *AgentOptions) WithHooks(hooks map[types.HookEvent][]types.HookMatcher) *AgentOptions {
+	if o.Hooks == nil {
+		o.Hooks = make(map[types.HookEvent][]types.HookMatcher)
+	}
+	for event, matchers := range hooks {
+		o.Hooks[event] = append(o.Hooks[event], matchers...)
+	}
+	return o
+}
+
 // WithCanUseTool sets the tool permission callback.
 func (o *AgentOptions) WithCanUseTool(callback CanUseToolCallback) *AgentOptions {
 	o.CanUseTool = callback
 	return o
 }
 
+// WithBudget attaches a shared spending budget.
+func (o *AgentOptions) WithBudget(budget *cost.Budget) *AgentOptions {
+	o.Budget = budget
+	return o
+}
+
+// WithRetry enables retries of transient Query failures.
+func (o *AgentOptions) WithRetry(policy *RetryPolicy) *AgentOptions {
+	o.Retry = policy
+	return o
+}
+
+// WithReconnect enables automatic reconnection of a Client.
+func (o *AgentOptions) WithReconnect(policy *ReconnectPolicy) *AgentOptions {
+	o.Reconnect = policy
+	return o
+}
+
 // WithEnv adds an environment variable.
 func (o *AgentOptions) WithEnv(key, value string) *AgentOptions {
 	if o.Env == nil {
@@ -214,6 +267,18 @@ func (o *AgentOptions) WithEnv(key, value string) *AgentOptions {
 	return o
 }
 
+// maxBudgetUSD returns MaxBudgetUSD capped at the remaining shared budget.
+func (o *AgentOptions) maxBudgetUSD() *float64 {
+	if o.Budget == nil {
+		return o.MaxBudgetUSD
+	}
+	remaining := o.Budget.Remaining()
+	if o.MaxBudgetUSD != nil {
+		remaining = math.Min(remaining, *o.MaxBudgetUSD)
+	}
+	return &remaining
+}
+
 // Clone creates a copy of the AgentOptions.
 func (o *AgentOptions) Clone() *AgentOptions {
 	if o == nil {
@@ -239,6 +304,7 @@ func (o *AgentOptions) Clone() *AgentOptions {
 		Resume:                   o.Resume,
 		MaxTurns:                 o.MaxTurns,
 		MaxBudgetUSD:             o.MaxBudgetUSD,
+		Budget:                   o.Budget,
 		Model:                    o.Model,
 		FallbackModel:            o.FallbackModel,
 		PermissionPromptToolName: o.PermissionPromptToolName,
@@ -249,6 +315,10 @@ func (o *AgentOptions) Clone() *AgentOptions {
 		MaxBufferSize:            o.MaxBufferSize,
 		Stderr:                   o.Stderr,
 		CanUseTool:               o.CanUseTool,
+		OnHookTimeout:            o.OnHookTimeout,
+		OnAsyncHookResult:        o.OnAsyncHookResult,
+		Retry:                    o.Retry,
+		Reconnect:                o.Reconnect,
 		User:                     o.User,
 		IncludePartialMessages:   o.IncludePartialMessages,
 		ForkSession:              o.ForkSession,
@@ -0,0 +1,443 @@
+package claude
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/nabkey/claude-agent-sdk-go/errors"
+	"github.com/nabkey/claude-agent-sdk-go/types"
+)
+
+// defaultPoolSize is the number of warm processes per option set when
+// PoolOptions.Size is zero.
+const defaultPoolSize = 2
+
+// PoolOptions configures a Pool.
+type PoolOptions struct {
+	// Size is the number of idle processes kept warm for each option set.
+	// Zero means 2.
+	Size int
+
+	// MaxUses is the number of queries a process serves before it is
+	// replaced. Zero means 1, so that every query starts a fresh
+	// conversation. Larger values reuse processes, and with them the
+	// conversation of earlier queries; use them only when that shared
+	// context is acceptable.
+	MaxUses int
+
+	// IdleTimeout closes processes that stay idle for this long. Zero keeps
+	// them until the Pool is closed.
+	IdleTimeout time.Duration
+}
+
+// Pool keeps streaming-mode CLI processes started ahead of time, so that
+// short one-shot queries do not pay for process startup. Processes are
+// grouped by option set: queries whose options would start the same CLI
+// command share a group. Options carrying callbacks, such as hooks,
+// CanUseTool, SDK MCP servers or a Budget, are only shared with queries
+// passing the same *AgentOptions, and are only kept warm once passed to
+// Warm; otherwise each query starts its own process.
+//
+// Each query checks a process out of its group. Once it is done, the
+// process is recycled and a replacement is started in the background.
+//
+// Example:
+//
+//	pool := claude.NewPool(claude.PoolOptions{Size: 4})
+//	defer pool.Close()
+//
+//	options := &claude.AgentOptions{Model: claude.String("claude-haiku-4-5")}
+//	if err := pool.Warm(ctx, options); err != nil {
+//	    log.Fatal(err)
+//	}
+//
+//	for msg, err := range pool.Query(ctx, "Classify: 'great product!'", options) {
+//	    // Process messages...
+//	}
+type Pool struct {
+	opts PoolOptions
+
+	ctx    context.Context // lifetime of pooled processes
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	groups map[string]*poolGroup
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// poolGroup holds the processes of one option set.
+type poolGroup struct {
+	key      string
+	options  *AgentOptions
+	idle     []*pooledClient
+	starting int
+
+	// identity is set for options keyed by pointer. Such groups are only
+	// kept warm once warmed, as a fresh *AgentOptions per query would
+	// otherwise leave Size processes behind on every call.
+	identity bool
+	warmed   bool
+}
+
+// pooledClient is a connected Client owned by a Pool.
+type pooledClient struct {
+	client    *Client
+	group     *poolGroup
+	uses      int
+	idleSince time.Time
+}
+
+// NewPool creates a Pool. Processes are started by Warm or on demand.
+func NewPool(opts PoolOptions) *Pool {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Pool{
+		opts:   opts,
+		ctx:    ctx,
+		cancel: cancel,
+		groups: make(map[string]*poolGroup),
+	}
+
+	if opts.IdleTimeout > 0 {
+		p.wg.Add(1)
+		go p.reapIdle()
+	}
+	return p
+}
+
+// Warm starts processes for options until its group has Size idle
+// processes, and waits for them to be ready. The group is then replenished
+// after each query, including for options carrying callbacks.
+func (p *Pool) Warm(ctx context.Context, options *AgentOptions) error {
+	if options == nil {
+		options = DefaultAgentOptions()
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return errPoolClosed()
+	}
+	g := p.group(options)
+	g.warmed = true
+	n := p.size() - len(g.idle) - g.starting
+	g.starting += max(n, 0)
+	p.mu.Unlock()
+
+	errs := make(chan error, max(n, 0))
+	for range n {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			errs <- p.start(g)
+		}()
+	}
+
+	var first error
+	for range n {
+		select {
+		case err := <-errs:
+			if err != nil && first == nil {
+				first = err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return first
+}
+
+// Query runs a one-shot query on a pooled process, like QuerySeq. Breaking
+// out of the loop interrupts the query and recycles the process.
+// Options.Retry is not applied.
+func (p *Pool) Query(ctx context.Context, prompt string, options *AgentOptions) iter.Seq2[types.Message, error] {
+	return func(yield func(types.Message, error) bool) {
+		if options == nil {
+			options = DefaultAgentOptions()
+		}
+
+		pc, err := p.acquire(ctx, options)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		// Closing the client ends the turn when ctx is cancelled
+		stop := context.AfterFunc(ctx, func() { _ = pc.client.Close() })
+		reusable := p.run(ctx, pc.client, prompt, yield)
+		if !stop() {
+			reusable = false
+		}
+
+		p.release(pc, reusable)
+	}
+}
+
+// run sends the query and yields its messages. It reports whether the
+// process finished the turn cleanly and may serve another query.
+func (p *Pool) run(ctx context.Context, client *Client, prompt string, yield func(types.Message, error) bool) bool {
+	turn, err := client.SendQuery(ctx, prompt)
+	if err != nil {
+		yield(nil, err)
+		return false
+	}
+
+	if !turn.iterate(yield) {
+		return false
+	}
+	if ctx.Err() != nil {
+		yield(nil, ctx.Err())
+		return false
+	}
+
+	result, err := turn.Wait(ctx)
+	return err == nil && result != nil
+}
+
+// Close closes all idle processes and stops warming new ones. Queries in
+// progress finish, and their processes are closed when released.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	p.cancel()
+
+	var idle []*pooledClient
+	for _, g := range p.groups {
+		idle = append(idle, g.idle...)
+		g.idle = nil
+	}
+	p.mu.Unlock()
+
+	for _, pc := range idle {
+		_ = pc.client.Close()
+	}
+	p.wg.Wait()
+	return nil
+}
+
+// acquire checks out an idle process of the option set, starting one if
+// none is ready, and starts a replacement in the background. ctx bounds the
+// startup of a process started on demand.
+func (p *Pool) acquire(ctx context.Context, options *AgentOptions) (*pooledClient, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, errPoolClosed()
+	}
+	g := p.group(options)
+
+	var pc *pooledClient
+	var dead []*pooledClient
+	for pc == nil && len(g.idle) > 0 {
+		candidate := g.idle[len(g.idle)-1]
+		g.idle = g.idle[:len(g.idle)-1]
+		if exited(candidate.client) {
+			dead = append(dead, candidate)
+			continue
+		}
+		pc = candidate
+	}
+	keep := p.keep(g)
+	if !keep {
+		delete(p.groups, g.key)
+	}
+	p.mu.Unlock()
+
+	for _, d := range dead {
+		_ = d.client.Close()
+	}
+
+	if pc == nil {
+		client, err := startClient(p.ctx, ctx, g.options)
+		if err != nil {
+			return nil, err
+		}
+		pc = &pooledClient{client: client, group: g}
+	}
+
+	if keep {
+		p.refill(g)
+	}
+	return pc, nil
+}
+
+// keep reports whether a group keeps processes between queries. Callers
+// hold p.mu.
+func (p *Pool) keep(g *poolGroup) bool {
+	return !g.identity || g.warmed
+}
+
+// release returns a process to its group or closes it.
+func (p *Pool) release(pc *pooledClient, reusable bool) {
+	pc.uses++
+
+	maxUses := p.opts.MaxUses
+	if maxUses <= 0 {
+		maxUses = 1
+	}
+
+	p.mu.Lock()
+	if reusable && pc.uses < maxUses && !p.closed && p.keep(pc.group) && !exited(pc.client) {
+		g := pc.group
+		pc.idleSince = time.Now()
+		g.idle = append(g.idle, pc)
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Unlock()
+
+	go func() { _ = pc.client.Close() }()
+}
+
+// refill starts processes in the background until the group has Size idle
+// or starting processes.
+func (p *Pool) refill(g *poolGroup) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return
+	}
+	for len(g.idle)+g.starting < p.size() {
+		g.starting++
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			_ = p.start(g)
+		}()
+	}
+}
+
+// start connects a process for a group whose starting count was already
+// incremented, and adds it to the idle list.
+func (p *Pool) start(g *poolGroup) error {
+	client, err := startClient(p.ctx, p.ctx, g.options)
+
+	p.mu.Lock()
+	g.starting--
+	if err == nil && p.closed {
+		err = errPoolClosed()
+	}
+	if err == nil {
+		g.idle = append(g.idle, &pooledClient{client: client, group: g, idleSince: time.Now()})
+	}
+	p.mu.Unlock()
+
+	if err != nil && client != nil {
+		_ = client.Close()
+	}
+	return err
+}
+
+// reapIdle closes processes idle for longer than IdleTimeout.
+func (p *Pool) reapIdle() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(max(p.opts.IdleTimeout/2, time.Second))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case now := <-ticker.C:
+			var expired []*pooledClient
+
+			p.mu.Lock()
+			for _, g := range p.groups {
+				kept := g.idle[:0]
+				for _, pc := range g.idle {
+					if now.Sub(pc.idleSince) >= p.opts.IdleTimeout {
+						expired = append(expired, pc)
+					} else {
+						kept = append(kept, pc)
+					}
+				}
+				g.idle = kept
+			}
+			p.mu.Unlock()
+
+			for _, pc := range expired {
+				_ = pc.client.Close()
+			}
+		}
+	}
+}
+
+// group returns the group of an option set, creating it if needed. Callers
+// hold p.mu.
+func (p *Pool) group(options *AgentOptions) *poolGroup {
+	key, identity := poolKey(options)
+	g, ok := p.groups[key]
+	if !ok {
+		g = &poolGroup{key: key, options: options, identity: identity}
+		p.groups[key] = g
+	}
+	return g
+}
+
+func (p *Pool) size() int {
+	if p.opts.Size > 0 {
+		return p.opts.Size
+	}
+	return defaultPoolSize
+}
+
+// poolKey identifies the option sets that start interchangeable processes.
+// Options holding callbacks or shared state are keyed by identity, as they
+// cannot be compared by value; identity reports that case.
+func poolKey(options *AgentOptions) (key string, identity bool) {
+	o := options.Clone()
+
+	stateful := o.Stderr != nil || o.CanUseTool != nil || len(o.Hooks) > 0 ||
+		o.OnHookTimeout != nil || o.OnAsyncHookResult != nil || o.Budget != nil
+	for _, config := range o.MCPServers {
+		if _, ok := config.(*types.SDKMCPServer); ok {
+			stateful = true
+		}
+	}
+	if stateful {
+		return fmt.Sprintf("%p", options), true
+	}
+
+	// Compare the fields that shape the CLI command. Retries and
+	// reconnects do not change the process that is started.
+	fields := make(map[string]any)
+	v := reflect.ValueOf(o).Elem()
+	for i := range v.NumField() {
+		name := v.Type().Field(i).Name
+		if v.Field(i).Kind() == reflect.Func || name == "Retry" || name == "Reconnect" {
+			continue
+		}
+		fields[name] = v.Field(i).Interface()
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return fmt.Sprintf("%p", options), true
+	}
+	return string(data), false
+}
+
+// exited reports whether a client's connection has ended.
+func exited(c *Client) bool {
+	select {
+	case <-c.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+func errPoolClosed() error {
+	return errors.NewCLIConnectionError("Pool is closed", nil)
+}
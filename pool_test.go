@@ -0,0 +1,150 @@
+package claude
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nabkey/claude-agent-sdk-go/types"
+)
+
+func TestPoolKey(t *testing.T) {
+	allowAll := func(ctx context.Context, toolName string, input map[string]any, permCtx types.ToolPermissionContext) (types.PermissionResult, error) {
+		return &types.PermissionResultAllow{}, nil
+	}
+	haiku := &AgentOptions{Model: String("claude-haiku-4-5")}
+	stateful := &AgentOptions{CanUseTool: allowAll}
+
+	tests := []struct {
+		name     string
+		a, b     *AgentOptions
+		same     bool
+		identity bool
+	}{
+		{"equal values", haiku, &AgentOptions{Model: String("claude-haiku-4-5")}, true, false},
+		{"different model", haiku, &AgentOptions{Model: String("claude-sonnet-4-5")}, false, false},
+		{"retry ignored", haiku, &AgentOptions{Model: String("claude-haiku-4-5"), Retry: &RetryPolicy{}}, true, false},
+		{"same callbacks", stateful, stateful, true, true},
+		{"equal callbacks", stateful, &AgentOptions{CanUseTool: allowAll}, false, true},
+	}
+
+	for _, tt := range tests {
+		a, identity := poolKey(tt.a)
+		b, _ := poolKey(tt.b)
+		if same := a == b; same != tt.same {
+			t.Errorf("%s: same key = %v, want %v", tt.name, same, tt.same)
+		}
+		if identity != tt.identity {
+			t.Errorf("%s: identity = %v, want %v", tt.name, identity, tt.identity)
+		}
+	}
+}
+
+// countTransports makes clients connect to fake transports answering with
+// echo, and returns the number of transports started.
+func countTransports(t *testing.T) *atomic.Int32 {
+	var started atomic.Int32
+	useFakeTransport(t, func() *fakeTransport {
+		started.Add(1)
+		return newFakeTransport(echo)
+	})
+	return &started
+}
+
+// waitStarted waits for background starts to bring the count to want.
+func waitStarted(t *testing.T, started *atomic.Int32, want int32) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for started.Load() < want && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := started.Load(); n != want {
+		t.Errorf("started %d processes, want %d", n, want)
+	}
+}
+
+func drain(t *testing.T, pool *Pool, options *AgentOptions) {
+	t.Helper()
+	for _, err := range pool.Query(context.Background(), "hi", options) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPoolRefillsValueGroups(t *testing.T) {
+	started := countTransports(t)
+	pool := NewPool(PoolOptions{Size: 2})
+	defer pool.Close()
+
+	drain(t, pool, &AgentOptions{Model: String("claude-haiku-4-5")})
+	waitStarted(t, started, 3)
+}
+
+func TestPoolDoesNotRefillIdentityGroups(t *testing.T) {
+	started := countTransports(t)
+	pool := NewPool(PoolOptions{Size: 2, MaxUses: 5})
+	defer pool.Close()
+
+	allowAll := func(ctx context.Context, toolName string, input map[string]any, permCtx types.ToolPermissionContext) (types.PermissionResult, error) {
+		return &types.PermissionResultAllow{}, nil
+	}
+	for range 3 {
+		drain(t, pool, &AgentOptions{CanUseTool: allowAll})
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if n := started.Load(); n != 3 {
+		t.Errorf("started %d processes, want 3", n)
+	}
+
+	pool.mu.Lock()
+	groups := len(pool.groups)
+	pool.mu.Unlock()
+	if groups != 0 {
+		t.Errorf("pool kept %d groups, want 0", groups)
+	}
+
+	// Warming opts an identity group into being kept warm
+	warm := &AgentOptions{CanUseTool: allowAll}
+	if err := pool.Warm(context.Background(), warm); err != nil {
+		t.Fatal(err)
+	}
+	drain(t, pool, warm)
+	waitStarted(t, started, 6)
+}
+
+func TestPoolQueryCancelledDuringStartup(t *testing.T) {
+	useFakeTransport(t, func() *fakeTransport {
+		fake := newFakeTransport(echo)
+		fake.hang = true
+		return fake
+	})
+	pool := NewPool(PoolOptions{})
+	defer pool.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		var first error
+		for _, err := range pool.Query(ctx, "hi", nil) {
+			if err != nil && first == nil {
+				first = err
+			}
+		}
+		done <- first
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Query succeeded, want a startup error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Query was not cancelled during startup")
+	}
+}
@@ -2,7 +2,9 @@ package claude
 
 import (
 	"context"
+	"iter"
 
+	"github.com/nabkey/claude-agent-sdk-go/cost"
 	"github.com/nabkey/claude-agent-sdk-go/internal/protocol"
 	"github.com/nabkey/claude-agent-sdk-go/internal/transport"
 	"github.com/nabkey/claude-agent-sdk-go/types"
@@ -69,91 +71,175 @@ func Query(ctx context.Context, prompt string, options *AgentOptions) <-chan any
 	go func() {
 		defer close(msgChan)
 
-		if options == nil {
-			options = DefaultAgentOptions()
-		}
+		runQueryWithRetry(ctx, prompt, options, func(item any) bool {
+			select {
+			case msgChan <- item:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
 
-		// Build transport options (non-streaming mode for Query)
-		transportOpts := &transport.SubprocessOptions{
-			SystemPrompt:           options.SystemPrompt,
-			AppendSystemPrompt:     options.AppendSystemPrompt,
-			Tools:                  options.Tools,
-			AllowedTools:           options.AllowedTools,
-			DisallowedTools:        options.DisallowedTools,
-			MaxTurns:               options.MaxTurns,
-			MaxBudgetUSD:           options.MaxBudgetUSD,
-			Model:                  options.Model,
-			FallbackModel:          options.FallbackModel,
-			PermissionMode:         options.PermissionMode,
-			ContinueConversation:   options.ContinueConversation,
-			Resume:                 options.Resume,
-			Settings:               options.Settings,
-			Sandbox:                options.Sandbox,
-			AddDirs:                options.AddDirs,
-			MCPServers:             options.MCPServers,
-			IncludePartialMessages: options.IncludePartialMessages,
-			ForkSession:            options.ForkSession,
-			Agents:                 options.Agents,
-			SettingSources:         options.SettingSources,
-			Plugins:                options.Plugins,
-			ExtraArgs:              options.ExtraArgs,
-			MaxThinkingTokens:      options.MaxThinkingTokens,
-			OutputFormat:           options.OutputFormat,
-			Betas:                  options.Betas,
-			CLIPath:                options.CLIPath,
-			Cwd:                    options.Cwd,
-			Env:                    options.Env,
-			MaxBufferSize:          options.MaxBufferSize,
-			Stderr:                 options.Stderr,
-			User:                   options.User,
-		}
+	return msgChan
+}
 
-		// Create transport (non-streaming mode)
-		trans, err := transport.NewSubprocessTransport(prompt, false, transportOpts)
-		if err != nil {
-			msgChan <- err
-			return
-		}
-		defer func() { _ = trans.Close() }()
+// QuerySeq executes a one-shot query like Query, returning an iterator of
+// messages and errors instead of a channel. The query runs while the loop
+// is iterating; breaking out of the loop stops it and closes the CLI
+// process.
+//
+// Example:
+//
+//	for msg, err := range claude.QuerySeq(ctx, "What is 2 + 2?", nil) {
+//	    if err != nil {
+//	        log.Printf("Error: %v", err)
+//	        continue
+//	    }
+//	    if result, ok := msg.(*types.ResultMessage); ok {
+//	        fmt.Println(*result.Result)
+//	        break
+//	    }
+//	}
+func QuerySeq(ctx context.Context, prompt string, options *AgentOptions) iter.Seq2[types.Message, error] {
+	return func(yield func(types.Message, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		runQueryWithRetry(ctx, prompt, options, func(item any) bool {
+			if err, ok := item.(error); ok {
+				return yield(nil, err)
+			}
+			return yield(item.(types.Message), nil)
+		})
+	}
+}
 
-		// Connect
-		if err := trans.Connect(ctx); err != nil {
-			msgChan <- err
+// runQuery runs a one-shot query, passing each message or error to emit.
+// It stops and closes the CLI process when emit returns false.
+func runQuery(ctx context.Context, prompt string, options *AgentOptions, emit func(any) bool) {
+	if options == nil {
+		options = DefaultAgentOptions()
+	}
+
+	var meter *cost.Meter
+	if options.Budget != nil {
+		if err := options.Budget.Check(); err != nil {
+			emit(err)
 			return
 		}
+		meter = options.Budget.Meter()
+	}
 
-		// Read messages
-		rawMsgChan, errChan := trans.ReadMessages(ctx)
+	// Build transport options (non-streaming mode for Query)
+	transportOpts := &transport.SubprocessOptions{
+		SystemPrompt:           options.SystemPrompt,
+		AppendSystemPrompt:     options.AppendSystemPrompt,
+		Tools:                  options.Tools,
+		AllowedTools:           options.AllowedTools,
+		DisallowedTools:        options.DisallowedTools,
+		MaxTurns:               options.MaxTurns,
+		MaxBudgetUSD:           options.maxBudgetUSD(),
+		Model:                  options.Model,
+		FallbackModel:          options.FallbackModel,
+		PermissionMode:         options.PermissionMode,
+		ContinueConversation:   options.ContinueConversation,
+		Resume:                 options.Resume,
+		Settings:               options.Settings,
+		Sandbox:                options.Sandbox,
+		AddDirs:                options.AddDirs,
+		MCPServers:             options.MCPServers,
+		IncludePartialMessages: options.IncludePartialMessages,
+		ForkSession:            options.ForkSession,
+		Agents:                 options.Agents,
+		SettingSources:         options.SettingSources,
+		Plugins:                options.Plugins,
+		ExtraArgs:              options.ExtraArgs,
+		MaxThinkingTokens:      options.MaxThinkingTokens,
+		OutputFormat:           options.OutputFormat,
+		Betas:                  options.Betas,
+		CLIPath:                options.CLIPath,
+		Cwd:                    options.Cwd,
+		Env:                    options.Env,
+		MaxBufferSize:          options.MaxBufferSize,
+		Stderr:                 options.Stderr,
+		User:                   options.User,
+	}
 
-		for {
-			select {
-			case <-ctx.Done():
-				msgChan <- ctx.Err()
-				return
+	// Create transport (non-streaming mode)
+	trans, err := transport.NewSubprocessTransport(prompt, false, transportOpts)
+	if err != nil {
+		emit(err)
+		return
+	}
+	defer func() { _ = trans.Close() }()
+
+	// Connect
+	if err := trans.Connect(ctx); err != nil {
+		emit(err)
+		return
+	}
+
+	// Read messages
+	rawMsgChan, errChan := trans.ReadMessages(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			emit(ctx.Err())
+			return
 
-			case err, ok := <-errChan:
-				if ok && err != nil {
-					msgChan <- err
+		case err, ok := <-errChan:
+			if ok && err != nil {
+				emit(err)
+			}
+			return
+
+		case raw, ok := <-rawMsgChan:
+			if !ok {
+				// The transport reports its error before closing the stream
+				if err, ok := <-errChan; ok && err != nil {
+					emit(err)
 				}
 				return
+			}
 
-			case raw, ok := <-rawMsgChan:
-				if !ok {
+			msg, err := protocol.ParseMessage(raw)
+			if err != nil {
+				if !emit(err) {
 					return
 				}
+				continue
+			}
+
+			// Charge the budget before emitting, so that a consumer that
+			// stops at the ResultMessage is still billed for it
+			var budgetErr error
+			if meter != nil {
+				budgetErr = meter.Observe(msg)
+			}
 
-				msg, err := protocol.ParseMessage(raw)
-				if err != nil {
-					msgChan <- err
-					continue
+			if !emit(msg) {
+				return
+			}
+
+			if am, ok := msg.(*types.AssistantMessage); ok {
+				if err := protocol.AssistantError(am); err != nil {
+					if !emit(err) {
+						return
+					}
 				}
+			}
 
-				msgChan <- msg
+			// Stop the process once a running turn exhausts the shared budget
+			if budgetErr != nil {
+				if _, done := msg.(*types.ResultMessage); !done {
+					emit(budgetErr)
+					return
+				}
 			}
 		}
-	}()
-
-	return msgChan
+	}
 }
 
 // QuerySync executes a query and collects all messages into a slice.
@@ -173,13 +259,12 @@ func QuerySync(ctx context.Context, prompt string, options *AgentOptions) ([]typ
 	var messages []types.Message
 	var lastError error
 
-	for msg := range Query(ctx, prompt, options) {
-		switch m := msg.(type) {
-		case types.Message:
-			messages = append(messages, m)
-		case error:
-			lastError = m
+	for msg, err := range QuerySeq(ctx, prompt, options) {
+		if err != nil {
+			lastError = err
+			continue
 		}
+		messages = append(messages, msg)
 	}
 
 	return messages, lastError
@@ -199,16 +284,17 @@ func QueryText(ctx context.Context, prompt string, options *AgentOptions) (strin
 	var text string
 	var lastError error
 
-	for msg := range Query(ctx, prompt, options) {
-		switch m := msg.(type) {
-		case *types.AssistantMessage:
+	for msg, err := range QuerySeq(ctx, prompt, options) {
+		if err != nil {
+			lastError = err
+			continue
+		}
+		if m, ok := msg.(*types.AssistantMessage); ok {
 			for _, block := range m.Content {
 				if textBlock, ok := block.(*types.TextBlock); ok {
 					text += textBlock.Text
 				}
 			}
-		case error:
-			lastError = m
 		}
 	}
 
@@ -0,0 +1,156 @@
+package claude
+
+import (
+	"context"
+	"time"
+
+	"github.com/nabkey/claude-agent-sdk-go/errors"
+	"github.com/nabkey/claude-agent-sdk-go/internal/protocol"
+)
+
+// Defaults for ReconnectPolicy fields left at zero.
+const (
+	defaultReconnectAttempts = 3
+	defaultReconnectBackoff  = time.Second
+	defaultReconnectMaxDelay = 30 * time.Second
+)
+
+// ReconnectPolicy configures how a Client recovers when the CLI process
+// fails while connected. A clean exit, such as after the input of
+// ConnectStream ends, is not treated as a failure. The Client starts a new process that resumes the
+// last session it saw, initializes it with the same hooks, permission
+// callback and SDK MCP servers, and carries on routing messages to the
+// Client's turns.
+//
+// The turn that was running when the process exited ends with the error
+// that stopped it and is not replayed; send the query again to retry it.
+// Input attached through ConnectStream is not resumed.
+//
+// The costs reported in ResultMessage are cumulative per process and start
+// again from zero in the new one. The Client meters its Budget afresh, but
+// a cost.Recorder fed with the Client's results must be Reset once the
+// failed turn has ended, as later results come from the new process.
+//
+// Example:
+//
+//	options := &claude.AgentOptions{
+//	    Reconnect: &claude.ReconnectPolicy{
+//	        MaxAttempts: 5,
+//	        OnReconnect: func(ev claude.ReconnectEvent) {
+//	            log.Printf("reconnect attempt %d: %v", ev.Attempt, ev.Err)
+//	        },
+//	    },
+//	}
+type ReconnectPolicy struct {
+	// MaxAttempts limits the restarts tried after each exit. Zero means 3.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first attempt. It doubles
+	// after each failed attempt. Zero means one second.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the delay between attempts. Zero means 30 seconds.
+	MaxBackoff time.Duration
+
+	// OnReconnect is called after every attempt, successful or not.
+	OnReconnect func(ReconnectEvent)
+}
+
+// ReconnectEvent describes one attempt to restart the CLI.
+type ReconnectEvent struct {
+	// Attempt is the 1-based number of the attempt.
+	Attempt int
+	// SessionID is the session being resumed, empty if none was seen.
+	SessionID string
+	// Cause is the error that ended the previous process.
+	Cause error
+	// Err is the error of the attempt, or nil if it succeeded.
+	Err error
+}
+
+func (p *ReconnectPolicy) maxAttempts() int {
+	if p.MaxAttempts > 0 {
+		return p.MaxAttempts
+	}
+	return defaultReconnectAttempts
+}
+
+// backoff returns the delay before the given 1-based attempt.
+func (p *ReconnectPolicy) backoff(attempt int) time.Duration {
+	delay := p.InitialBackoff
+	if delay <= 0 {
+		delay = defaultReconnectBackoff
+	}
+	limit := p.MaxBackoff
+	if limit <= 0 {
+		limit = defaultReconnectMaxDelay
+	}
+
+	for i := 1; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+	return min(delay, limit)
+}
+
+// reconnect restarts the CLI after the connection of q failed with cause.
+// It returns the new query, or nil if reconnecting is disabled, the client
+// was closed or every attempt failed.
+func (c *Client) reconnect(q *protocol.Query, cause error, sessionID string) *protocol.Query {
+	c.mu.Lock()
+	policy := c.options.Reconnect
+	stop := c.stop
+	current := c.connected && c.query == q
+	c.mu.Unlock()
+
+	// A nil cause is a clean exit, which the caller asked for
+	if policy == nil || !current || cause == nil {
+		return nil
+	}
+
+	opts := c.options.Clone()
+	if sessionID != "" {
+		opts.Resume = &sessionID
+		opts.ContinueConversation = false
+	}
+
+	for attempt := 1; attempt <= policy.maxAttempts(); attempt++ {
+		select {
+		case <-time.After(policy.backoff(attempt)):
+		case <-stop:
+			return nil
+		}
+
+		// The new process outlives this call, so it gets its own context
+		trans, next, err := c.open(context.Background(), opts)
+		if err == nil {
+			c.mu.Lock()
+			if c.connected && c.query == q {
+				c.transport = trans
+				c.query = next
+				// The new process reports its cost from zero
+				if opts.Budget != nil {
+					c.meter = opts.Budget.Meter()
+				}
+			} else {
+				err = errors.NewCLIConnectionError("Client closed while reconnecting", nil)
+			}
+			c.mu.Unlock()
+		}
+
+		if err != nil && next != nil {
+			_ = next.Close()
+		}
+		if policy.OnReconnect != nil {
+			policy.OnReconnect(ReconnectEvent{
+				Attempt:   attempt,
+				SessionID: sessionID,
+				Cause:     cause,
+				Err:       err,
+			})
+		}
+		if err == nil {
+			return next
+		}
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+package claude
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"github.com/nabkey/claude-agent-sdk-go/errors"
+	"github.com/nabkey/claude-agent-sdk-go/types"
+)
+
+// Defaults for RetryPolicy fields left at zero.
+const (
+	defaultRetryAttempts = 3
+	defaultRetryBackoff  = time.Second
+	defaultRetryMaxDelay = time.Minute
+	defaultResumePrompt  = "Continue where you left off."
+)
+
+// RetryPolicy re-runs a Query whose attempt fails transiently: the result
+// has the error_during_execution subtype, or the CLI reports a rate_limit,
+// server_error or overloaded API error.
+//
+// Messages are streamed as they arrive, so the caller sees the messages of
+// a failed attempt before those of the retry. The error and error result
+// that caused a retry are not delivered; OnRetry reports them instead. When
+// no attempts remain, they are delivered as usual.
+//
+// Example:
+//
+//	options := &claude.AgentOptions{
+//	    Retry: &claude.RetryPolicy{
+//	        MaxAttempts: 5,
+//	        Jitter:      0.2,
+//	        OnRetry: func(ev claude.RetryEvent) {
+//	            log.Printf("attempt %d failed, retrying in %s: %v", ev.Attempt, ev.Delay, ev.Err)
+//	        },
+//	    },
+//	}
+//	answer, err := claude.QueryText(ctx, "Summarize the changelog", options)
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	// Zero means 3.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry. It doubles after
+	// each retry. Zero means one second.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the delay between attempts. Zero means one minute.
+	MaxBackoff time.Duration
+
+	// Jitter randomizes each delay by up to this fraction in either
+	// direction, from 0 to 1. Zero disables jitter.
+	Jitter float64
+
+	// Resume makes retries resume the failed attempt's session, so that
+	// Claude keeps the work done so far, instead of starting over. The
+	// session already holds the original prompt, so a resumed retry sends
+	// ResumePrompt instead.
+	Resume bool
+
+	// ResumePrompt is the prompt of resumed retries. Empty means
+	// "Continue where you left off."
+	ResumePrompt string
+
+	// ShouldRetry overrides the default classification of failures. It
+	// receives either the error reported during an attempt or the
+	// ResultMessage of a failed attempt.
+	ShouldRetry func(err error, result *types.ResultMessage) bool
+
+	// OnRetry is called before each retry.
+	OnRetry func(RetryEvent)
+}
+
+// RetryEvent describes a failed attempt that is about to be retried.
+type RetryEvent struct {
+	// Attempt is the 1-based number of the attempt that failed.
+	Attempt int
+	// Delay is the wait before the next attempt.
+	Delay time.Duration
+	// Err is the error that failed the attempt, if any.
+	Err error
+	// Result is the error result of the attempt, if any.
+	Result *types.ResultMessage
+	// SessionID is the session of the failed attempt, if known.
+	SessionID string
+}
+
+func (p *RetryPolicy) maxAttempts() int {
+	if p.MaxAttempts > 0 {
+		return p.MaxAttempts
+	}
+	return defaultRetryAttempts
+}
+
+func (p *RetryPolicy) resumePrompt() string {
+	if p.ResumePrompt != "" {
+		return p.ResumePrompt
+	}
+	return defaultResumePrompt
+}
+
+// shouldRetry reports whether a failure is transient.
+func (p *RetryPolicy) shouldRetry(err error, result *types.ResultMessage) bool {
+	if p.ShouldRetry != nil {
+		return p.ShouldRetry(err, result)
+	}
+	if result != nil {
+		return result.IsError && result.Subtype == "error_during_execution"
+	}
+	apiErr, ok := errors.AsAPIError(err)
+	return ok && apiErr.Retryable()
+}
+
+// delay returns the wait after the given 1-based attempt, honoring the
+// Retry-After hint of err.
+func (p *RetryPolicy) delay(attempt int, err error) time.Duration {
+	delay := p.InitialBackoff
+	if delay <= 0 {
+		delay = defaultRetryBackoff
+	}
+	limit := p.MaxBackoff
+	if limit <= 0 {
+		limit = defaultRetryMaxDelay
+	}
+
+	for i := 1; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+	delay = min(delay, limit)
+
+	if p.Jitter > 0 {
+		jitter := min(p.Jitter, 1)
+		delay = time.Duration(float64(delay) * (1 + jitter*(2*rand.Float64()-1)))
+	}
+
+	if apiErr, ok := errors.AsAPIError(err); ok && apiErr.RetryAfter > delay {
+		delay = apiErr.RetryAfter
+	}
+	return delay
+}
+
+// runQueryWithRetry runs a one-shot query, retrying transient failures as
+// configured by options.Retry.
+func runQueryWithRetry(ctx context.Context, prompt string, options *AgentOptions, emit func(any) bool) {
+	if options == nil || options.Retry == nil {
+		runQuery(ctx, prompt, options, emit)
+		return
+	}
+
+	policy := options.Retry
+	attemptOpts := options
+	attemptPrompt := prompt
+
+	for attempt := 1; ; attempt++ {
+		last := attempt >= policy.maxAttempts()
+
+		var (
+			stopped   bool
+			failErr   error
+			failRes   *types.ResultMessage
+			sessionID string
+			held      []any // items after a transient failure, delivered if not retried
+		)
+
+		runQuery(ctx, attemptPrompt, attemptOpts, func(item any) bool {
+			if msg, ok := item.(types.Message); ok {
+				if id := messageSessionID(msg); id != "" {
+					sessionID = id
+				}
+			}
+
+			if !last && failErr == nil && failRes == nil {
+				switch v := item.(type) {
+				case error:
+					if policy.shouldRetry(v, nil) {
+						failErr = v
+					}
+				case *types.ResultMessage:
+					if policy.shouldRetry(nil, v) {
+						failRes = v
+					}
+				}
+			}
+
+			if failErr != nil || failRes != nil {
+				held = append(held, item)
+				return true
+			}
+			if !emit(item) {
+				stopped = true
+				return false
+			}
+			return true
+		})
+
+		if stopped || ctx.Err() != nil {
+			return
+		}
+		if failErr == nil && failRes == nil {
+			return
+		}
+
+		delay := policy.delay(attempt, failErr)
+		if policy.OnRetry != nil {
+			policy.OnRetry(RetryEvent{
+				Attempt:   attempt,
+				Delay:     delay,
+				Err:       failErr,
+				Result:    failRes,
+				SessionID: sessionID,
+			})
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			for _, item := range held {
+				if !emit(item) {
+					return
+				}
+			}
+			emit(ctx.Err())
+			return
+		}
+
+		if policy.Resume && sessionID != "" {
+			attemptOpts = options.Clone()
+			attemptOpts.Resume = &sessionID
+			attemptOpts.ContinueConversation = false
+			attemptPrompt = policy.resumePrompt()
+		}
+	}
+}
+
+// messageSessionID returns the session ID carried by msg, if any.
+func messageSessionID(msg types.Message) string {
+	switch m := msg.(type) {
+	case *types.ResultMessage:
+		return m.SessionID
+	case *types.AssistantMessage:
+		return m.SessionID
+	case *types.SystemMessage:
+		if m.Init != nil {
+			return m.Init.SessionID
+		}
+	}
+	return ""
+}
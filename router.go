@@ -0,0 +1,190 @@
+package claude
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nabkey/claude-agent-sdk-go/errors"
+	"github.com/nabkey/claude-agent-sdk-go/types"
+)
+
+// maxUnclaimedTurns is the number of finished turns the router keeps for
+// ReceiveMessages and ReceiveResponse when nothing has read them.
+const maxUnclaimedTurns = 64
+
+// router delivers parsed messages to turns. A CLI process runs a single
+// conversation and answers queries in the order they were sent, so turns
+// form one queue: messages go to the oldest unfinished turn, and a turn
+// leaves the queue once it is both finished and claimed by a reader.
+type router struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	turns  []*Turn
+	closed bool
+
+	// interrupt stops the running turn when a reader abandons it
+	interrupt func(ctx context.Context) error
+}
+
+func newRouter() *router {
+	r := &router{}
+	r.cond = sync.NewCond(&r.mu)
+	return r
+}
+
+// begin queues a turn for a query about to be sent. An untracked turn still
+// collecting unsolicited messages is ended first, so that the query's
+// messages go to the new turn.
+func (r *router) begin() (*Turn, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil, errors.NewCLIConnectionError("Not connected. Call Connect() first.", nil)
+	}
+
+	if open := r.open(); open != nil && !open.explicit {
+		open.finish(nil)
+		r.prune()
+	}
+
+	t := newTurn(r, true)
+	r.turns = append(r.turns, t)
+	r.cond.Broadcast()
+	return t, nil
+}
+
+// abort removes a turn whose query could not be sent.
+func (r *router) abort(t *Turn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, queued := range r.turns {
+		if queued == t {
+			r.turns = append(r.turns[:i:i], r.turns[i+1:]...)
+			break
+		}
+	}
+	t.finish(errConnectionClosed())
+}
+
+// deliver adds msg to the oldest unfinished turn. Messages that arrive
+// while no turn is pending start an untracked turn.
+func (r *router) deliver(msg types.Message) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t := r.open()
+	if t == nil {
+		t = newTurn(r, false)
+		r.turns = append(r.turns, t)
+		r.cond.Broadcast()
+	}
+
+	t.push(msg)
+	if t.isFinished() {
+		r.prune()
+	}
+}
+
+// claimTurn marks a turn as owned by its Turn handle.
+func (r *router) claimTurn(t *Turn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t.claimed = true
+	r.prune()
+}
+
+// claimNext claims the oldest unclaimed turn. When wait is set, it blocks
+// until such a turn exists; it returns nil once the connection has ended
+// and no unclaimed turns remain.
+func (r *router) claimNext(wait bool) *Turn {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for {
+		for _, t := range r.turns {
+			if !t.claimed {
+				t.claimed = true
+				r.prune()
+				return t
+			}
+		}
+		if r.closed || !wait {
+			return nil
+		}
+		r.cond.Wait()
+	}
+}
+
+// open returns the oldest unfinished turn. Callers hold r.mu.
+func (r *router) open() *Turn {
+	for _, t := range r.turns {
+		if !t.isFinished() {
+			return t
+		}
+	}
+	return nil
+}
+
+// prune drops turns that are finished and claimed, and the oldest finished
+// turns nobody claimed beyond maxUnclaimedTurns. Callers hold r.mu.
+func (r *router) prune() {
+	unclaimed := 0
+	for _, t := range r.turns {
+		if !t.claimed && t.isFinished() {
+			unclaimed++
+		}
+	}
+
+	turns := r.turns[:0]
+	for _, t := range r.turns {
+		if t.isFinished() {
+			if t.claimed {
+				continue
+			}
+			if unclaimed > maxUnclaimedTurns {
+				unclaimed--
+				continue
+			}
+		}
+		turns = append(turns, t)
+	}
+	clear(r.turns[len(turns):])
+	r.turns = turns
+}
+
+// interruptTurns ends all unfinished turns with err while keeping the
+// router open, when the CLI has been restarted.
+func (r *router) interruptTurns(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err == nil {
+		err = errConnectionClosed()
+	}
+	for _, t := range r.turns {
+		t.finish(err)
+	}
+	r.prune()
+}
+
+// close ends all unfinished turns once the message stream has ended, with
+// err or, if the stream ended cleanly, a connection error. Unclaimed turns
+// stay queued so their messages can still be read.
+func (r *router) close(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err == nil {
+		err = errConnectionClosed()
+	}
+
+	r.closed = true
+	for _, t := range r.turns {
+		t.finish(err)
+	}
+	r.prune()
+	r.cond.Broadcast()
+}
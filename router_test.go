@@ -0,0 +1,312 @@
+package claude
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nabkey/claude-agent-sdk-go/internal/transport"
+	"github.com/nabkey/claude-agent-sdk-go/types"
+)
+
+// fakeTransport plays the CLI side of the streaming protocol. It answers
+// control requests and passes each query's prompt to onQuery, which sends
+// the response through emit.
+type fakeTransport struct {
+	onQuery func(f *fakeTransport, prompt string)
+	hang    bool // Connect blocks until its context is done
+
+	mu          sync.Mutex
+	msgs        chan map[string]any
+	errs        chan error
+	closed      bool
+	prompts     []string
+	interrupts  int
+	onInterrupt func(f *fakeTransport)
+}
+
+func newFakeTransport(onQuery func(f *fakeTransport, prompt string)) *fakeTransport {
+	return &fakeTransport{
+		onQuery: onQuery,
+		msgs:    make(chan map[string]any, 1000),
+		errs:    make(chan error, 1),
+	}
+}
+
+// useFakeTransport makes clients created during the test connect to
+// transports built by create.
+func useFakeTransport(t *testing.T, create func() *fakeTransport) {
+	t.Helper()
+	previous := newTransport
+	newTransport = func(opts *transport.SubprocessOptions) (transport.Transport, error) {
+		return create(), nil
+	}
+	t.Cleanup(func() { newTransport = previous })
+}
+
+// connectFake returns a client connected to a fake transport that answers
+// each query with onQuery.
+func connectFake(t *testing.T, onQuery func(f *fakeTransport, prompt string)) (*Client, *fakeTransport) {
+	t.Helper()
+	fake := newFakeTransport(onQuery)
+	useFakeTransport(t, func() *fakeTransport { return fake })
+
+	client, err := NewClient(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Connect(context.Background(), ""); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client, fake
+}
+
+func (f *fakeTransport) Connect(ctx context.Context) error {
+	if f.hang {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return nil
+}
+
+func (f *fakeTransport) Write(ctx context.Context, data string) error {
+	var msg map[string]any
+	if err := json.Unmarshal([]byte(data), &msg); err != nil {
+		return err
+	}
+
+	switch msg["type"] {
+	case "control_request":
+		request, _ := msg["request"].(map[string]any)
+		f.emit(map[string]any{
+			"type": "control_response",
+			"response": map[string]any{
+				"subtype":    "success",
+				"request_id": msg["request_id"],
+				"response":   map[string]any{},
+			},
+		})
+		if request["subtype"] == "interrupt" {
+			f.mu.Lock()
+			f.interrupts++
+			onInterrupt := f.onInterrupt
+			f.mu.Unlock()
+			if onInterrupt != nil {
+				onInterrupt(f)
+			}
+		}
+	case "user":
+		message, _ := msg["message"].(map[string]any)
+		prompt, _ := message["content"].(string)
+		f.mu.Lock()
+		f.prompts = append(f.prompts, prompt)
+		f.mu.Unlock()
+		if f.onQuery != nil {
+			f.onQuery(f, prompt)
+		}
+	}
+	return nil
+}
+
+func (f *fakeTransport) ReadMessages(ctx context.Context) (<-chan map[string]any, <-chan error) {
+	return f.msgs, f.errs
+}
+
+func (f *fakeTransport) EndInput() error { return nil }
+
+func (f *fakeTransport) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.closed {
+		f.closed = true
+		close(f.msgs)
+		close(f.errs)
+	}
+	return nil
+}
+
+func (f *fakeTransport) IsReady() bool { return true }
+
+// emit sends a message from the CLI side.
+func (f *fakeTransport) emit(msg map[string]any) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.closed {
+		f.msgs <- msg
+	}
+}
+
+func (f *fakeTransport) text(text string) {
+	f.emit(map[string]any{
+		"type": "assistant",
+		"message": map[string]any{
+			"model":   "claude-test",
+			"content": []any{map[string]any{"type": "text", "text": text}},
+		},
+	})
+}
+
+func (f *fakeTransport) result(result string) {
+	f.emit(map[string]any{
+		"type":       "result",
+		"subtype":    "success",
+		"session_id": "session",
+		"result":     result,
+	})
+}
+
+func (f *fakeTransport) interruptCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.interrupts
+}
+
+// echo answers every query with its prompt, as text and as result.
+func echo(f *fakeTransport, prompt string) {
+	f.text(prompt)
+	f.result(prompt)
+}
+
+func waitResult(t *testing.T, turn *Turn) string {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := turn.Wait(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result == nil || result.Result == nil {
+		t.Fatal("turn finished without a result")
+	}
+	return *result.Result
+}
+
+func texts(turn *Turn) []string {
+	var out []string
+	for msg := range turn.Messages() {
+		if am, ok := msg.(*types.AssistantMessage); ok {
+			for _, block := range am.Content {
+				if tb, ok := block.(*types.TextBlock); ok {
+					out = append(out, tb.Text)
+				}
+			}
+		}
+	}
+	return out
+}
+
+func TestRouterOutOfOrderWait(t *testing.T) {
+	client, _ := connectFake(t, echo)
+	ctx := context.Background()
+
+	var turns []*Turn
+	for _, prompt := range []string{"first", "second", "third"} {
+		turn, err := client.SendQuery(ctx, prompt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		turns = append(turns, turn)
+	}
+
+	for _, i := range []int{2, 0, 1} {
+		want := []string{"first", "second", "third"}[i]
+		if got := waitResult(t, turns[i]); got != want {
+			t.Errorf("turn %d result = %q, want %q", i, got, want)
+		}
+		if got := texts(turns[i]); len(got) != 1 || got[0] != want {
+			t.Errorf("turn %d texts = %q, want [%q]", i, got, want)
+		}
+	}
+}
+
+func TestRouterUnclaimedTurnOverflow(t *testing.T) {
+	client, _ := connectFake(t, echo)
+	ctx := context.Background()
+
+	const extra = 6
+	var turns []*Turn
+	for i := range maxUnclaimedTurns + extra {
+		turn, err := client.SendQuery(ctx, fmt.Sprintf("q%d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		turns = append(turns, turn)
+	}
+	select {
+	case <-turns[len(turns)-1].Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("last turn did not finish")
+	}
+
+	// The oldest unclaimed turns are forgotten by ReceiveResponse...
+	var first string
+	for msg := range client.ReceiveResponse() {
+		if result, ok := msg.(*types.ResultMessage); ok {
+			first = *result.Result
+		}
+	}
+	if want := fmt.Sprintf("q%d", extra); first != want {
+		t.Errorf("ReceiveResponse result = %q, want %q", first, want)
+	}
+
+	// ...but remain readable through their Turn
+	if got := waitResult(t, turns[0]); got != "q0" {
+		t.Errorf("forgotten turn result = %q, want %q", got, "q0")
+	}
+}
+
+func TestRouterCancelMidTurn(t *testing.T) {
+	client, fake := connectFake(t, func(f *fakeTransport, prompt string) {
+		f.text(prompt)
+		if prompt != "slow" {
+			f.result(prompt)
+		}
+	})
+	// The CLI ends an interrupted turn with its result
+	fake.mu.Lock()
+	fake.onInterrupt = func(f *fakeTransport) {
+		f.text("late")
+		f.result("interrupted")
+	}
+	fake.mu.Unlock()
+	ctx := context.Background()
+
+	slow, err := client.SendQuery(ctx, "slow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, err := range slow.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		break
+	}
+
+	select {
+	case <-slow.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancelled turn was not interrupted")
+	}
+	if n := fake.interruptCount(); n != 1 {
+		t.Errorf("interrupts = %d, want 1", n)
+	}
+
+	// The rest of the cancelled turn does not leak into the next one
+	next, err := client.SendQuery(ctx, "next")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := texts(next); len(got) != 1 || got[0] != "next" {
+		t.Errorf("next turn texts = %q, want [%q]", got, "next")
+	}
+	if got := waitResult(t, next); got != "next" {
+		t.Errorf("next turn result = %q, want %q", got, "next")
+	}
+}
@@ -0,0 +1,202 @@
+package claude
+
+import (
+	"context"
+	"iter"
+	"sync"
+
+	"github.com/nabkey/claude-agent-sdk-go/errors"
+	"github.com/nabkey/claude-agent-sdk-go/internal/protocol"
+	"github.com/nabkey/claude-agent-sdk-go/types"
+)
+
+// DefaultSessionID is the session served by the Client's own CLI process,
+// which Client.SendQuery, Client.ReceiveResponse and Client.ReceiveMessages
+// use.
+const DefaultSessionID = "default"
+
+// Session is a logical conversation of a Client, such as the chat of one
+// tenant. A CLI process runs a single conversation, so every Session other
+// than the default one runs its own CLI process, started with the Client's
+// options on first use. Messages are kept apart by process: a Session only
+// ever receives the messages of its own conversation.
+//
+// Sessions share the Client's hooks, permission callback, SDK MCP servers
+// and Budget. Closing the Client closes all of its sessions.
+//
+// Example:
+//
+//	alice := client.Session("tenant-alice")
+//	bob := client.Session("tenant-bob")
+//
+//	turn, err := alice.SendQuery(ctx, "Summarize my open tickets")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	_, _ = bob.SendQuery(ctx, "Draft a reply to the last email")
+//
+//	for msg := range turn.Messages() {
+//	    // Only messages for tenant-alice...
+//	}
+type Session struct {
+	parent *Client
+	id     string
+
+	mu     sync.Mutex
+	client *Client // nil until the session's process is started
+	closed bool
+}
+
+// Session returns the session with the given ID, creating it if needed.
+// The empty ID and DefaultSessionID refer to the Client's own process.
+func (c *Client) Session(id string) *Session {
+	if id == "" || id == DefaultSessionID {
+		return &Session{parent: c, id: DefaultSessionID, client: c}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if s, ok := c.sessions[id]; ok {
+		return s
+	}
+	if c.sessions == nil {
+		c.sessions = make(map[string]*Session)
+	}
+	s := &Session{parent: c, id: id}
+	c.sessions[id] = s
+	return s
+}
+
+// ID returns the session ID.
+func (s *Session) ID() string {
+	return s.id
+}
+
+// SendQuery sends a new query to Claude in this session, starting the
+// session's CLI process if needed. ctx bounds the startup, not the life of
+// the process.
+func (s *Session) SendQuery(ctx context.Context, prompt string) (*Turn, error) {
+	client, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.SendQuery(ctx, prompt)
+}
+
+// SendMessage sends a new query made of content blocks in this session.
+func (s *Session) SendMessage(ctx context.Context, content ...types.ContentBlock) (*Turn, error) {
+	if _, err := protocol.MarshalContentBlocks(content); err != nil {
+		return nil, err
+	}
+	client, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.SendMessage(ctx, content...)
+}
+
+// ReceiveMessages returns a channel that yields all messages of this
+// session. The channel is closed immediately if nothing was sent yet.
+func (s *Session) ReceiveMessages() <-chan types.Message {
+	return s.current().ReceiveMessages()
+}
+
+// ReceiveResponse yields messages of this session until a ResultMessage is
+// received.
+func (s *Session) ReceiveResponse() <-chan types.Message {
+	return s.current().ReceiveResponse()
+}
+
+// ReceiveMessagesSeq returns an iterator over all messages of this session.
+func (s *Session) ReceiveMessagesSeq() iter.Seq2[types.Message, error] {
+	return s.current().ReceiveMessagesSeq()
+}
+
+// ReceiveResponseSeq returns an iterator over the messages of this
+// session's next response.
+func (s *Session) ReceiveResponseSeq() iter.Seq2[types.Message, error] {
+	return s.current().ReceiveResponseSeq()
+}
+
+// Interrupt stops the running turn of this session.
+func (s *Session) Interrupt(ctx context.Context) error {
+	s.mu.Lock()
+	client := s.client
+	s.mu.Unlock()
+
+	if client == nil {
+		return errors.NewCLIConnectionError("Not connected. Call Connect() first.", nil)
+	}
+	return client.Interrupt(ctx)
+}
+
+// Close stops the session's CLI process. Closing the default session
+// closes the Client. A closed session cannot be used again.
+func (s *Session) Close() error {
+	if s.id == DefaultSessionID {
+		return s.parent.Close()
+	}
+
+	s.parent.mu.Lock()
+	if s.parent.sessions[s.id] == s {
+		delete(s.parent.sessions, s.id)
+	}
+	s.parent.mu.Unlock()
+
+	return s.close()
+}
+
+// close stops the session's process without unregistering it.
+func (s *Session) close() error {
+	s.mu.Lock()
+	client := s.client
+	s.client = nil
+	s.closed = true
+	s.mu.Unlock()
+
+	if client == nil {
+		return nil
+	}
+	return client.Close()
+}
+
+// current returns the client serving the session, or an unconnected one if
+// its process has not been started.
+func (s *Session) current() *Client {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.client == nil {
+		return &Client{options: s.parent.options}
+	}
+	return s.client
+}
+
+// connect returns the client serving the session, starting its process on
+// first use while the parent Client is connected.
+func (s *Session) connect(ctx context.Context) (*Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.client != nil {
+		return s.client, nil
+	}
+	if s.closed {
+		return nil, errors.NewCLIConnectionError("Session is closed", nil)
+	}
+
+	s.parent.mu.Lock()
+	connected := s.parent.connected
+	s.parent.mu.Unlock()
+	if !connected {
+		return nil, errors.NewCLIConnectionError("Not connected. Call Connect() first.", nil)
+	}
+
+	client, err := startClient(context.Background(), ctx, s.parent.options)
+	if err != nil {
+		return nil, err
+	}
+	s.client = client
+	return client, nil
+}
@@ -0,0 +1,341 @@
+package shell
+
+import (
+	"fmt"
+	"strings"
+)
+
+// tokenKind distinguishes words from operators.
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenOperator
+)
+
+// token is a single lexical element of a command line.
+type token struct {
+	kind tokenKind
+	val  string
+	// subs holds the bodies of command substitutions found inside a word.
+	subs []string
+}
+
+// operators lists the control and redirection operators, longest first so
+// that the lexer always matches greedily.
+var operators = []string{
+	"&>>", "<<<", "<<-", ";;&",
+	"&&", "||", "|&", ";;", ";&", ">>", ">|", ">&", "<&", "<>", "<<", "&>",
+	"|", "&", ";", "(", ")", "<", ">", "\n",
+}
+
+// lexer splits a command line into words and operators.
+type lexer struct {
+	src      string
+	pos      int
+	tokens   []token
+	heredocs []heredoc
+	// expectHeredoc is set after a << operator so the next word is recorded
+	// as a here-document delimiter.
+	expectHeredoc bool
+	stripTabs     bool
+}
+
+// heredoc is a pending here-document whose body starts after the next newline.
+type heredoc struct {
+	delimiter string
+	stripTabs bool
+}
+
+// lex tokenizes src.
+func lex(src string) ([]token, error) {
+	l := &lexer{src: src}
+	if err := l.run(); err != nil {
+		return nil, err
+	}
+	return l.tokens, nil
+}
+
+func (l *lexer) run() error {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\r':
+			l.pos++
+
+		case c == '\\' && l.pos+1 < len(l.src) && l.src[l.pos+1] == '\n':
+			// Line continuation
+			l.pos += 2
+
+		case c == '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+
+		case (c == '<' || c == '>') && l.pos+1 < len(l.src) && l.src[l.pos+1] == '(':
+			// Process substitution behaves like a word whose body is a command.
+			body, err := l.readBalanced(l.pos+2, ')')
+			if err != nil {
+				return err
+			}
+			l.emit(token{kind: tokenWord, val: l.src[l.pos : l.pos+len(body)+3], subs: []string{body}})
+			l.pos += len(body) + 3
+
+		default:
+			if op := l.matchOperator(); op != "" {
+				l.pos += len(op)
+				l.emit(token{kind: tokenOperator, val: op})
+				if op == "\n" {
+					l.skipHeredocBodies()
+				}
+				continue
+			}
+			if err := l.readWord(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if l.expectHeredoc {
+		return fmt.Errorf("missing here-document delimiter")
+	}
+	return nil
+}
+
+// emit appends a token and tracks here-document delimiters.
+func (l *lexer) emit(t token) {
+	if t.kind == tokenOperator && (t.val == "<<" || t.val == "<<-") {
+		l.expectHeredoc = true
+		l.stripTabs = t.val == "<<-"
+	} else if t.kind == tokenWord && l.expectHeredoc {
+		l.heredocs = append(l.heredocs, heredoc{delimiter: t.val, stripTabs: l.stripTabs})
+		l.expectHeredoc = false
+	}
+	l.tokens = append(l.tokens, t)
+}
+
+// matchOperator returns the operator starting at the current position, if any.
+func (l *lexer) matchOperator() string {
+	rest := l.src[l.pos:]
+	for _, op := range operators {
+		if strings.HasPrefix(rest, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// skipHeredocBodies consumes the bodies of pending here-documents, which
+// start on the line following their redirection.
+func (l *lexer) skipHeredocBodies() {
+	for _, h := range l.heredocs {
+		for l.pos < len(l.src) {
+			end := strings.IndexByte(l.src[l.pos:], '\n')
+			var line string
+			if end < 0 {
+				line = l.src[l.pos:]
+				l.pos = len(l.src)
+			} else {
+				line = l.src[l.pos : l.pos+end]
+				l.pos += end + 1
+			}
+			if h.stripTabs {
+				line = strings.TrimLeft(line, "\t")
+			}
+			if line == h.delimiter {
+				break
+			}
+		}
+	}
+	l.heredocs = nil
+}
+
+// readWord reads a word, removing quotes and collecting command substitutions.
+func (l *lexer) readWord() error {
+	var word strings.Builder
+	var subs []string
+
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\r':
+			l.emitWord(word.String(), subs)
+			return nil
+
+		case c == '\'':
+			end := strings.IndexByte(l.src[l.pos+1:], '\'')
+			if end < 0 {
+				return fmt.Errorf("unterminated single quote")
+			}
+			word.WriteString(l.src[l.pos+1 : l.pos+1+end])
+			l.pos += end + 2
+
+		case c == '"':
+			l.pos++
+			closed := false
+			for l.pos < len(l.src) {
+				d := l.src[l.pos]
+				if d == '"' {
+					l.pos++
+					closed = true
+					break
+				}
+				if d == '\\' && l.pos+1 < len(l.src) && strings.IndexByte("\"\\$`\n", l.src[l.pos+1]) >= 0 {
+					word.WriteByte(l.src[l.pos+1])
+					l.pos += 2
+					continue
+				}
+				if d == '$' || d == '`' {
+					sub, n, err := l.readSubstitution()
+					if err != nil {
+						return err
+					}
+					if sub != "" {
+						subs = append(subs, sub)
+					}
+					word.WriteString(l.src[l.pos : l.pos+n])
+					l.pos += n
+					continue
+				}
+				word.WriteByte(d)
+				l.pos++
+			}
+			if !closed {
+				return fmt.Errorf("unterminated double quote")
+			}
+
+		case c == '\\':
+			if l.pos+1 < len(l.src) {
+				word.WriteByte(l.src[l.pos+1])
+				l.pos += 2
+			} else {
+				l.pos++
+			}
+
+		case c == '$' || c == '`':
+			sub, n, err := l.readSubstitution()
+			if err != nil {
+				return err
+			}
+			if sub != "" {
+				subs = append(subs, sub)
+			}
+			word.WriteString(l.src[l.pos : l.pos+n])
+			l.pos += n
+
+		default:
+			if l.matchOperator() != "" {
+				// A run of digits directly before a redirection is a file
+				// descriptor, which belongs to the operator.
+				if isFD(word.String()) && len(subs) == 0 && (c == '<' || c == '>') {
+					fd := word.String()
+					op := l.matchOperator()
+					l.pos += len(op)
+					l.emit(token{kind: tokenOperator, val: fd + op})
+					return nil
+				}
+				l.emitWord(word.String(), subs)
+				return nil
+			}
+			word.WriteByte(c)
+			l.pos++
+		}
+	}
+
+	l.emitWord(word.String(), subs)
+	return nil
+}
+
+func (l *lexer) emitWord(word string, subs []string) {
+	l.emit(token{kind: tokenWord, val: word, subs: subs})
+}
+
+// readSubstitution handles `$(...)`, `$((...))`, `${...}` and backticks at
+// the current position. It returns the body of a command substitution (empty
+// for other expansions) and the number of source bytes consumed.
+func (l *lexer) readSubstitution() (string, int, error) {
+	rest := l.src[l.pos:]
+
+	switch {
+	case strings.HasPrefix(rest, "$(("):
+		body, err := l.readBalanced(l.pos+3, ')')
+		if err != nil {
+			return "", 0, err
+		}
+		// Arithmetic expansion ends with "))"
+		n := len(body) + 4
+		if l.pos+n < len(l.src) && l.src[l.pos+n] == ')' {
+			n++
+		}
+		return "", n, nil
+
+	case strings.HasPrefix(rest, "$("):
+		body, err := l.readBalanced(l.pos+2, ')')
+		if err != nil {
+			return "", 0, err
+		}
+		return body, len(body) + 3, nil
+
+	case strings.HasPrefix(rest, "${"):
+		end := strings.IndexByte(rest, '}')
+		if end < 0 {
+			return "", 0, fmt.Errorf("unterminated parameter expansion")
+		}
+		return "", end + 1, nil
+
+	case strings.HasPrefix(rest, "`"):
+		end := strings.IndexByte(rest[1:], '`')
+		if end < 0 {
+			return "", 0, fmt.Errorf("unterminated backquote")
+		}
+		return rest[1 : end+1], end + 2, nil
+	}
+
+	return "", 1, nil
+}
+
+// readBalanced returns the text from start up to the matching close
+// character, honoring nested parentheses and quotes.
+func (l *lexer) readBalanced(start int, close byte) (string, error) {
+	depth := 1
+	var quote byte
+
+	for i := start; i < len(l.src); i++ {
+		c := l.src[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' {
+				i++
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '\\':
+			i++
+		case c == '(':
+			depth++
+		case c == close:
+			depth--
+			if depth == 0 {
+				return l.src[start:i], nil
+			}
+		}
+	}
+	return "", fmt.Errorf("unterminated command substitution")
+}
+
+// isFD reports whether s is a non-empty string of digits.
+func isFD(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+package shell
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLex(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{"ls -la", []string{"ls", "-la"}},
+		{"echo 'a b' \"c d\"", []string{"echo", "a b", "c d"}},
+		{`echo a\ b "x\"y"`, []string{"echo", "a b", `x"y`}},
+		{"a && b || c; d & e | f |& g", []string{"a", "&&", "b", "||", "c", ";", "d", "&", "e", "|", "f", "|&", "g"}},
+		{"cmd 2>&1 >>out <in", []string{"cmd", "2>&", "1", ">>", "out", "<", "in"}},
+		{"cmd &>all 2>err", []string{"cmd", "&>", "all", "2>", "err"}},
+		{"echo a#b # comment", []string{"echo", "a#b"}},
+		{"echo a \\\n b", []string{"echo", "a", "b"}},
+		{"(cd sub)", []string{"(", "cd", "sub", ")"}},
+		{"case x in a) ls;; b) pwd;& esac", []string{"case", "x", "in", "a", ")", "ls", ";;", "b", ")", "pwd", ";&", "esac"}},
+		{"cat <<EOF\nrm -rf /\nEOF\nls", []string{"cat", "<<", "EOF", "\n", "ls"}},
+		{"cat <<-EOF\n\trm -rf /\n\tEOF\nls", []string{"cat", "<<-", "EOF", "\n", "ls"}},
+		{"echo $(date) ${HOME} $((1+2))", []string{"echo", "$(date)", "${HOME}", "$((1+2))"}},
+		{"diff <(ls a) <(ls b)", []string{"diff", "<(ls a)", "<(ls b)"}},
+	}
+
+	for _, tt := range tests {
+		tokens, err := lex(tt.src)
+		if err != nil {
+			t.Errorf("lex(%q) error: %v", tt.src, err)
+			continue
+		}
+		var got []string
+		for _, tok := range tokens {
+			got = append(got, tok.val)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("lex(%q) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestLexSubstitutions(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{"echo $(rm -rf /)", []string{"rm -rf /"}},
+		{"echo \"$(rm x)\"", []string{"rm x"}},
+		{"echo `rm x`", []string{"rm x"}},
+		{"echo '$(rm x)'", nil},
+		{"echo $((1+2)) ${x}", nil},
+		{"echo $(echo $(rm x))", []string{"echo $(rm x)"}},
+	}
+
+	for _, tt := range tests {
+		tokens, err := lex(tt.src)
+		if err != nil {
+			t.Errorf("lex(%q) error: %v", tt.src, err)
+			continue
+		}
+		var got []string
+		for _, tok := range tokens {
+			got = append(got, tok.subs...)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("lex(%q) substitutions = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestLexErrors(t *testing.T) {
+	for _, src := range []string{
+		"echo 'unterminated",
+		"echo \"unterminated",
+		"echo $(unterminated",
+		"echo `unterminated",
+		"echo ${unterminated",
+		"cat <<",
+	} {
+		if _, err := lex(src); err == nil {
+			t.Errorf("lex(%q) succeeded, want an error", src)
+		}
+	}
+}
@@ -0,0 +1,416 @@
+package shell
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// wrapperOptions lists, for programs that run another command, the options
+// that consume a following argument.
+var wrapperOptions = map[string]map[string]bool{
+	"sudo":    {"-u": true, "-g": true, "-C": true, "-h": true, "-p": true, "-U": true, "-r": true, "-t": true, "-D": true},
+	"doas":    {"-u": true, "-C": true},
+	"env":     {"-u": true, "-C": true, "-S": true},
+	"nice":    {"-n": true},
+	"nohup":   {},
+	"exec":    {"-a": true},
+	"builtin": {},
+	"timeout": {"-s": true, "-k": true, "--signal": true, "--kill-after": true},
+	"stdbuf":  {"-i": true, "-o": true, "-e": true},
+	"xargs":   {"-I": true, "-n": true, "-P": true, "-d": true, "-L": true, "-s": true, "-E": true, "-a": true},
+	"watch":   {"-n": true},
+	"command": {},
+	// Multi-call binaries run the applet named by their first operand
+	"busybox": {},
+	"toybox":  {},
+}
+
+// shells are interpreters whose -c argument is itself a command line.
+var shells = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "dash": true, "ksh": true, "fish": true,
+}
+
+// expand adds the commands that cmd runs on behalf of the caller: the
+// wrapped program of wrappers, `sh -c` scripts, `eval` arguments and
+// `find -exec` commands.
+func (s *Script) expand(cmd *Command) error {
+	program := cmd.Program()
+
+	if shells[program] {
+		if source, script := shellInvocation(cmd.Args); source == sourceCommand {
+			return s.parse(script, true)
+		}
+		return nil
+	}
+
+	switch program {
+	case "eval":
+		return s.parse(strings.Join(cmd.Args, " "), true)
+
+	case "find":
+		for i := 0; i < len(cmd.Args); i++ {
+			switch cmd.Args[i] {
+			case "-exec", "-execdir", "-ok", "-okdir":
+				var words []string
+				for i++; i < len(cmd.Args) && cmd.Args[i] != ";" && cmd.Args[i] != "+"; i++ {
+					words = append(words, cmd.Args[i])
+				}
+				if err := s.add(words); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	options, ok := wrapperOptions[program]
+	if !ok {
+		return nil
+	}
+
+	args := cmd.Args
+	for len(args) > 0 {
+		arg := args[0]
+		switch {
+		case arg == "--":
+			args = args[1:]
+			return s.add(args)
+		case program == "env" && assignmentPattern.MatchString(arg):
+			args = args[1:]
+		case program == "command" && (arg == "-v" || arg == "-V"):
+			// Only describes the command without running it
+			return nil
+		case program == "env" && arg == "-S" && len(args) > 1:
+			if err := s.parse(args[1], true); err != nil {
+				return err
+			}
+			args = args[2:]
+		case strings.HasPrefix(arg, "-"):
+			if options[arg] {
+				args = args[1:]
+			}
+			args = args[1:]
+		case program == "timeout":
+			// The first operand of timeout is the duration
+			return s.add(args[1:])
+		case program == "nice" && isFD(strings.TrimPrefix(arg, "-")):
+			args = args[1:]
+		default:
+			return s.add(args)
+		}
+	}
+	return nil
+}
+
+// shellOptions lists the shell options that consume a following argument.
+var shellOptions = map[string]bool{
+	"-o": true, "+o": true, "-O": true, "+O": true, "--rcfile": true, "--init-file": true,
+}
+
+// scriptSource is where a shell invocation reads its commands from.
+type scriptSource int
+
+const (
+	sourceStdin   scriptSource = iota // standard input: a pipe, here-string or terminal
+	sourceCommand                     // the -c command string
+	sourceFile                        // a script file
+)
+
+// shellInvocation returns where a shell reads its commands from, and the
+// command string or script file. -c and -s may be combined with other short
+// options as in `bash -lc`; the command string or script file is the first
+// operand after the options.
+func shellInvocation(args []string) (scriptSource, string) {
+	command, stdin := false, false
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			i++
+		case shellOptions[arg]:
+			i++
+			continue
+		case len(arg) > 1 && arg[0] == '-' && arg[1] != '-':
+			command = command || strings.ContainsRune(arg[1:], 'c')
+			stdin = stdin || strings.ContainsRune(arg[1:], 's')
+			continue
+		case len(arg) > 1 && (arg[0] == '+' || arg[0] == '-'):
+			// Other long options and +x style options take no argument
+			continue
+		}
+
+		if i >= len(args) {
+			break
+		}
+		switch {
+		case command:
+			return sourceCommand, args[i]
+		case stdin || args[i] == "-":
+			return sourceStdin, ""
+		default:
+			return sourceFile, args[i]
+		}
+	}
+	if command {
+		return sourceCommand, ""
+	}
+	return sourceStdin, ""
+}
+
+// opaque reports whether the commands cmd runs cannot be read from the
+// command line.
+func opaque(cmd *Command) bool {
+	if hasExpansion(cmd.Name) {
+		return true
+	}
+	if shells[cmd.Program()] {
+		source, _ := shellInvocation(cmd.Args)
+		return source == sourceStdin
+	}
+	return false
+}
+
+// hasExpansion reports whether a word may expand to something other than
+// its text: it contains a parameter, command or arithmetic expansion, a
+// glob or a brace expansion. Quotes have been removed by then, so quoted
+// characters count as well.
+func hasExpansion(word string) bool {
+	if strings.ContainsAny(word, "$`*?") {
+		return true
+	}
+	if strings.Contains(word, "[") && strings.Contains(word, "]") {
+		return true
+	}
+	open := strings.Index(word, "{")
+	return open >= 0 && strings.Contains(word[open:], "}") &&
+		(strings.Contains(word[open:], ",") || strings.Contains(word[open:], ".."))
+}
+
+// chdirOptions lists, for wrappers, the options that run the wrapped
+// command in another directory.
+var chdirOptions = map[string]string{
+	"env":  "-C",
+	"sudo": "-D",
+}
+
+// changesDirectory reports whether cmd changes the working directory of
+// the commands that follow it or that it runs.
+func changesDirectory(cmd *Command) bool {
+	switch program := cmd.Program(); program {
+	case "cd", "pushd", "popd":
+		return true
+	case "env", "sudo":
+		for _, arg := range cmd.Args {
+			if arg == chdirOptions[program] || arg == "--chdir" || strings.HasPrefix(arg, "--chdir=") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// add appends a nested command built from words and expands it.
+func (s *Script) add(words []string) error {
+	if len(words) == 0 {
+		return nil
+	}
+	cmd := &Command{Name: words[0], Args: words[1:], Nested: true}
+	s.Commands = append(s.Commands, cmd)
+	return s.expand(cmd)
+}
+
+// writtenOperands returns the files a well-known program writes to.
+func writtenOperands(cmd *Command) []string {
+	switch cmd.Program() {
+	case "tee", "touch", "rm", "rmdir", "unlink", "shred":
+		return operands(cmd.Args, nil)
+
+	case "mkdir":
+		return operands(cmd.Args, map[string]bool{"-m": true, "--mode": true})
+
+	case "truncate":
+		return operands(cmd.Args, map[string]bool{"-s": true, "--size": true, "-r": true, "--reference": true})
+
+	case "cp", "mv", "ln", "install", "rsync":
+		for i, arg := range cmd.Args {
+			if arg == "-t" && i+1 < len(cmd.Args) {
+				return []string{cmd.Args[i+1]}
+			}
+			if v, ok := strings.CutPrefix(arg, "--target-directory="); ok {
+				return []string{v}
+			}
+		}
+		ops := operands(cmd.Args, map[string]bool{"-m": true, "-o": true, "-g": true, "-S": true})
+		if len(ops) >= 2 {
+			return ops[len(ops)-1:]
+		}
+		if len(ops) == 1 && cmd.Program() == "ln" {
+			// `ln -s target` creates the link in the current directory
+			return []string{filepath.Base(ops[0])}
+		}
+
+	case "chmod", "chown", "chgrp":
+		ops := operands(cmd.Args, nil)
+		for _, arg := range cmd.Args {
+			if strings.HasPrefix(arg, "--reference=") {
+				return ops
+			}
+		}
+		// The first operand is the mode or owner
+		if len(ops) > 0 {
+			return ops[1:]
+		}
+
+	case "tar":
+		return tarWrites(cmd.Args)
+
+	case "git":
+		// git -C runs in, and writes to, another repository
+		var dirs []string
+		for i := 0; i < len(cmd.Args) && strings.HasPrefix(cmd.Args[i], "-"); i++ {
+			if cmd.Args[i] == "-C" && i+1 < len(cmd.Args) {
+				i++
+				dirs = append(dirs, cmd.Args[i])
+			}
+		}
+		return dirs
+
+	case "dd":
+		for _, arg := range cmd.Args {
+			if v, ok := strings.CutPrefix(arg, "of="); ok {
+				return []string{v}
+			}
+		}
+
+	case "curl", "wget":
+		for i, arg := range cmd.Args {
+			if (arg == "-o" || arg == "-O" || arg == "--output" || arg == "--output-document") && i+1 < len(cmd.Args) {
+				return []string{cmd.Args[i+1]}
+			}
+			if v, ok := strings.CutPrefix(arg, "--output="); ok {
+				return []string{v}
+			}
+			if v, ok := strings.CutPrefix(arg, "--output-document="); ok {
+				return []string{v}
+			}
+		}
+
+	case "sed":
+		inPlace, hasScript := false, false
+		for _, arg := range cmd.Args {
+			if strings.HasPrefix(arg, "-i") || strings.HasPrefix(arg, "--in-place") {
+				inPlace = true
+			}
+			if arg == "-e" || arg == "-f" || arg == "--expression" || arg == "--file" {
+				hasScript = true
+			}
+		}
+		if !inPlace {
+			return nil
+		}
+		ops := operands(cmd.Args, map[string]bool{"-e": true, "-f": true, "--expression": true, "--file": true})
+		if !hasScript && len(ops) > 0 {
+			ops = ops[1:]
+		}
+		return ops
+	}
+
+	return nil
+}
+
+// tarWrites returns the archive tar creates or the directories it extracts
+// to. Options may be bundled, with or without a leading dash, as in
+// `tar xzf archive.tgz -C dir`.
+func tarWrites(args []string) []string {
+	var archive string
+	var dirs []string
+	create, extract := false, false
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			i = len(args)
+		case arg == "--create" || arg == "--append" || arg == "--update" || arg == "--concatenate":
+			create = true
+		case arg == "--extract" || arg == "--get":
+			extract = true
+		case arg == "--file" || arg == "--directory":
+			if i+1 < len(args) {
+				i++
+				if arg == "--file" {
+					archive = args[i]
+				} else {
+					dirs = append(dirs, args[i])
+				}
+			}
+		case strings.HasPrefix(arg, "--file="):
+			archive = strings.TrimPrefix(arg, "--file=")
+		case strings.HasPrefix(arg, "--directory="):
+			dirs = append(dirs, strings.TrimPrefix(arg, "--directory="))
+		case strings.HasPrefix(arg, "--"):
+			// Other long options
+		case strings.HasPrefix(arg, "-") || i == 0:
+			// Without a dash, values are the following arguments in order;
+			// with one, a value may also be the rest of the bundle
+			dashed := strings.HasPrefix(arg, "-")
+			bundle := strings.TrimPrefix(arg, "-")
+			for j := 0; j < len(bundle); j++ {
+				switch bundle[j] {
+				case 'c', 'r', 'u', 'A':
+					create = true
+				case 'x':
+					extract = true
+				case 'f', 'C':
+					var value string
+					if dashed && j+1 < len(bundle) {
+						value = bundle[j+1:]
+					} else if i+1 < len(args) {
+						i++
+						value = args[i]
+					}
+					if bundle[j] == 'f' {
+						archive = value
+					} else {
+						dirs = append(dirs, value)
+					}
+					if dashed {
+						j = len(bundle)
+					}
+				}
+			}
+		}
+	}
+
+	var files []string
+	if create && archive != "" && archive != "-" {
+		files = append(files, archive)
+	}
+	if extract {
+		if len(dirs) == 0 {
+			dirs = []string{"."}
+		}
+		files = append(files, dirs...)
+	}
+	return files
+}
+
+// operands returns the non-option arguments, skipping the values of options
+// listed in withValue. Everything after "--" is an operand.
+func operands(args []string, withValue map[string]bool) []string {
+	var ops []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return append(ops, args[i+1:]...)
+		}
+		if strings.HasPrefix(arg, "-") && arg != "-" {
+			if withValue[arg] {
+				i++
+			}
+			continue
+		}
+		ops = append(ops, arg)
+	}
+	return ops
+}
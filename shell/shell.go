@@ -0,0 +1,340 @@
+// Package shell tokenizes shell command lines so that permission callbacks
+// can reason about the programs a Bash tool call invokes and the files it
+// writes.
+//
+// The parser understands pipelines, command lists (`;`, `&&`, `||`, `&`),
+// subshells and groups, redirections, here-documents, environment
+// assignments, command and process substitution, and common wrapper
+// programs such as sudo, env, command, xargs, busybox and `bash -lc`. It
+// does not expand variables or globs; such words are reported verbatim, and
+// Command.Opaque reports commands whose program cannot be known without
+// running them.
+package shell
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Redirect is an I/O redirection attached to a command.
+type Redirect struct {
+	// FD is the explicit file descriptor before the operator, if any (e.g. "2" in "2>").
+	FD string
+	// Op is the redirection operator, e.g. ">", ">>", "<", "&>", ">&".
+	Op string
+	// Target is the file name, here-document delimiter or duplicated descriptor.
+	Target string
+}
+
+// Writes reports whether the redirection opens Target for writing.
+func (r Redirect) Writes() bool {
+	switch r.Op {
+	case ">", ">>", ">|", "&>", "&>>", "<>":
+		return true
+	case ">&":
+		// ">&N" and ">&-" duplicate or close a descriptor; ">&file" writes.
+		return !isFD(r.Target) && r.Target != "-"
+	}
+	return false
+}
+
+// Command is a single simple command.
+type Command struct {
+	// Env holds leading NAME=value assignments.
+	Env map[string]string
+	// Name is the program being invoked, as written.
+	Name string
+	// Args are the arguments following Name.
+	Args []string
+	// Redirects are the redirections attached to the command.
+	Redirects []Redirect
+	// Nested is true for commands found inside a substitution or run
+	// through a wrapper such as sudo or `bash -c`.
+	Nested bool
+}
+
+// Program returns the base name of the invoked program.
+func (c *Command) Program() string {
+	return filepath.Base(c.Name)
+}
+
+// Words returns Name followed by Args.
+func (c *Command) Words() []string {
+	if c.Name == "" {
+		return c.Args
+	}
+	return append([]string{c.Name}, c.Args...)
+}
+
+// HasPrefix reports whether the command's program and leading arguments
+// match the given words. The program is compared by base name, so
+// "git status" matches "/usr/bin/git status --short".
+func (c *Command) HasPrefix(words ...string) bool {
+	if len(words) == 0 || c.Name == "" {
+		return false
+	}
+	if words[0] != c.Program() && words[0] != c.Name {
+		return false
+	}
+	if len(words)-1 > len(c.Args) {
+		return false
+	}
+	for i, w := range words[1:] {
+		if c.Args[i] != w {
+			return false
+		}
+	}
+	return true
+}
+
+// WrittenFiles returns the files the command may create, modify or delete,
+// as described for Script.WrittenFiles.
+func (c *Command) WrittenFiles() []string {
+	var files []string
+	for _, r := range c.Redirects {
+		if r.Writes() {
+			files = append(files, r.Target)
+		}
+	}
+	return append(files, writtenOperands(c)...)
+}
+
+// ChangesDirectory reports whether the command changes the working
+// directory for the commands after it, as cd, pushd and popd do, or runs a
+// wrapped command in another directory, as `env -C` does. Relative paths
+// written after such a command are not relative to the original directory.
+func (c *Command) ChangesDirectory() bool {
+	return changesDirectory(c)
+}
+
+// Opaque reports whether the commands this command runs cannot be known
+// from the command line: its name contains an expansion, as in
+// `$(echo rm) -rf /`, or it is a shell reading its commands from standard
+// input, as in `curl -s URL | sh` or `bash <<< "rm -rf /"`. Shells run
+// with -c are not opaque; their command string is parsed instead.
+func (c *Command) Opaque() bool {
+	return opaque(c)
+}
+
+// Script is a parsed command line.
+type Script struct {
+	// Commands lists every simple command in execution order, including
+	// commands nested in substitutions and wrappers.
+	Commands []*Command
+}
+
+// Parse tokenizes and parses a shell command line.
+//
+// Example:
+//
+//	script, err := shell.Parse("cd src && go test ./... > /tmp/out.txt")
+//	if err != nil {
+//	    return err
+//	}
+//	fmt.Println(script.Programs())     // [cd go]
+//	fmt.Println(script.WrittenFiles()) // [/tmp/out.txt]
+func Parse(command string) (*Script, error) {
+	script := &Script{}
+	if err := script.parse(command, false); err != nil {
+		return nil, err
+	}
+	return script, nil
+}
+
+// Programs returns the distinct programs invoked by the script, by base name.
+func (s *Script) Programs() []string {
+	seen := make(map[string]bool)
+	var programs []string
+	for _, cmd := range s.Commands {
+		if cmd.Name == "" {
+			continue
+		}
+		p := cmd.Program()
+		if !seen[p] {
+			seen[p] = true
+			programs = append(programs, p)
+		}
+	}
+	return programs
+}
+
+// WrittenFiles returns the files the script may create, modify or delete:
+// output redirection targets plus the operands of well-known file-writing
+// programs (tee, cp, mv, rm, touch, mkdir, dd, ...). The program-based
+// detection is a heuristic and does not cover every tool.
+func (s *Script) WrittenFiles() []string {
+	seen := make(map[string]bool)
+	var files []string
+	add := func(f string) {
+		if f != "" && !seen[f] {
+			seen[f] = true
+			files = append(files, f)
+		}
+	}
+
+	for _, cmd := range s.Commands {
+		for _, f := range cmd.WrittenFiles() {
+			add(f)
+		}
+	}
+	return files
+}
+
+// Words returns every word in the script that may name a file: arguments,
+// redirection targets and assignment values.
+func (s *Script) Words() []string {
+	var words []string
+	for _, cmd := range s.Commands {
+		for _, v := range cmd.Env {
+			words = append(words, v)
+		}
+		words = append(words, cmd.Words()...)
+		for _, r := range cmd.Redirects {
+			if r.Op != "<<" && r.Op != "<<-" {
+				words = append(words, r.Target)
+			}
+		}
+	}
+	return words
+}
+
+// reservedWords start or end compound commands and are skipped when they
+// appear in command position.
+var reservedWords = map[string]bool{
+	"if": true, "then": true, "else": true, "elif": true, "fi": true,
+	"do": true, "done": true, "while": true, "until": true,
+	"esac": true, "{": true, "}": true, "!": true, "time": true,
+}
+
+// caseTerminators end a case clause; the next clause starts with a pattern.
+var caseTerminators = map[string]bool{
+	";;": true, ";&": true, ";;&": true,
+}
+
+// assignmentPattern matches a leading NAME=value word.
+var assignmentPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=`)
+
+// parse appends the commands in src to the script.
+func (s *Script) parse(src string, nested bool) error {
+	tokens, err := lex(src)
+	if err != nil {
+		return err
+	}
+
+	cmd := &Command{Nested: nested}
+	skipping := false // inside a for/select/function header
+	cases := 0        // depth of open case statements
+	pattern := false  // inside a case header or clause pattern
+
+	flush := func() error {
+		if cmd.Name != "" || len(cmd.Redirects) > 0 || len(cmd.Env) > 0 {
+			s.Commands = append(s.Commands, cmd)
+			if err := s.expand(cmd); err != nil {
+				return err
+			}
+		}
+		cmd = &Command{Nested: nested}
+		return nil
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+
+		for _, sub := range t.subs {
+			if err := s.parse(sub, true); err != nil {
+				return err
+			}
+		}
+
+		if t.kind == tokenOperator {
+			if fd, op, ok := splitRedirect(t.val); ok {
+				if i+1 >= len(tokens) || tokens[i+1].kind != tokenWord {
+					return fmt.Errorf("missing target for redirection %q", t.val)
+				}
+				i++
+				for _, sub := range tokens[i].subs {
+					if err := s.parse(sub, true); err != nil {
+						return err
+					}
+				}
+				cmd.Redirects = append(cmd.Redirects, Redirect{FD: fd, Op: op, Target: tokens[i].val})
+				continue
+			}
+
+			if pattern {
+				// A pattern ends at ")"; "(", "|" and newlines belong to it
+				pattern = t.val != ")"
+				continue
+			}
+
+			// Control operator: end the current command
+			skipping = false
+			if err := flush(); err != nil {
+				return err
+			}
+			if cases > 0 && caseTerminators[t.val] {
+				pattern = true
+			}
+			continue
+		}
+
+		if pattern {
+			if t.val == "esac" {
+				cases--
+				pattern = false
+			}
+			continue
+		}
+
+		if skipping {
+			continue
+		}
+
+		if cmd.Name == "" {
+			switch {
+			case t.val == "case":
+				// Skip the word and "in", then the first pattern
+				cases++
+				pattern = true
+				continue
+			case t.val == "esac" && cases > 0:
+				cases--
+				continue
+			case reservedWords[t.val]:
+				continue
+			case t.val == "for" || t.val == "select" || t.val == "function":
+				skipping = true
+				continue
+			case assignmentPattern.MatchString(t.val):
+				if cmd.Env == nil {
+					cmd.Env = make(map[string]string)
+				}
+				name, value, _ := strings.Cut(t.val, "=")
+				cmd.Env[name] = value
+				continue
+			}
+			cmd.Name = t.val
+			continue
+		}
+
+		cmd.Args = append(cmd.Args, t.val)
+	}
+
+	return flush()
+}
+
+// splitRedirect splits a redirection operator into its descriptor and operator.
+func splitRedirect(val string) (fd, op string, ok bool) {
+	i := 0
+	for i < len(val) && val[i] >= '0' && val[i] <= '9' {
+		i++
+	}
+	fd, op = val[:i], val[i:]
+	switch op {
+	case ">", ">>", ">|", ">&", "<", "<&", "<>", "<<", "<<-", "<<<", "&>", "&>>":
+		return fd, op, true
+	}
+	return "", "", false
+}
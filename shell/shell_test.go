@@ -0,0 +1,161 @@
+package shell
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPrograms(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{"ls -la | grep go", []string{"ls", "grep"}},
+		{"FOO=1 /usr/bin/git status && make", []string{"git", "make"}},
+		{"echo $(whoami) > out", []string{"whoami", "echo"}},
+		{"(cd sub; go test ./...)", []string{"cd", "go"}},
+		{"if test -f x; then cat x; else touch x; fi", []string{"test", "cat", "touch"}},
+		{"for f in $(ls); do rm $f; done", []string{"ls", "rm"}},
+		{"while true; do sleep 1; done", []string{"true", "sleep"}},
+		{"case x in x) rm y;; esac", []string{"rm"}},
+		{"case $(id) in a|b) ls;; (c) pwd;& *) cat;;& esac; echo done", []string{"id", "ls", "pwd", "cat", "echo"}},
+		{"case x in\n  a)\n    ls\n    ;;\nesac", []string{"ls"}},
+		{"sudo -u root env FOO=1 nice -n 5 rm x", []string{"sudo", "env", "nice", "rm"}},
+		{"timeout 5 curl x", []string{"timeout", "curl"}},
+		{"xargs -n 1 rm", []string{"xargs", "rm"}},
+		{"busybox rm x", []string{"busybox", "rm"}},
+		{"command -v rm", []string{"command"}},
+		{"bash -lc 'git pull && make'", []string{"bash", "git", "make"}},
+		{"sh -c -- 'ls'", []string{"sh", "ls"}},
+		{"eval 'rm x'", []string{"eval", "rm"}},
+		{"find . -name '*.go' -exec gofmt -l {} +", []string{"find", "gofmt"}},
+		{"env -S 'rm x'", []string{"env", "rm"}},
+		{"cat <<EOF\nrm -rf /\nEOF", []string{"cat"}},
+	}
+
+	for _, tt := range tests {
+		script, err := Parse(tt.command)
+		if err != nil {
+			t.Errorf("Parse(%q) error: %v", tt.command, err)
+			continue
+		}
+		if got := script.Programs(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q).Programs() = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestWrittenFiles(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{"echo x > a 2>> b &> c 2>&1", []string{"a", "b", "c"}},
+		{"cat < in >&out", []string{"out"}},
+		{"tee -a log1 log2", []string{"log1", "log2"}},
+		{"rm -rf build dist", []string{"build", "dist"}},
+		{"mkdir -m 755 -p dir", []string{"dir"}},
+		{"cp -r src dst", []string{"dst"}},
+		{"mv -t dir a b", []string{"dir"}},
+		{"ln -s /etc/passwd link", []string{"link"}},
+		{"ln -s /etc/passwd", []string{"passwd"}},
+		{"chmod -R 755 bin", []string{"bin"}},
+		{"chown --reference=ref a b", []string{"a", "b"}},
+		{"tar -xzf archive.tgz -C /opt", []string{"/opt"}},
+		{"tar xzf archive.tgz", []string{"."}},
+		{"tar --extract --file archive.tar --directory=out", []string{"out"}},
+		{"tar czf out.tgz src", []string{"out.tgz"}},
+		{"tar -cf- src", nil},
+		{"tar tzf archive.tgz", nil},
+		{"git -C /srv/repo checkout .", []string{"/srv/repo"}},
+		{"git status", nil},
+		{"dd if=/dev/zero of=disk.img", []string{"disk.img"}},
+		{"curl -o page.html https://example.com", []string{"page.html"}},
+		{"sed -i 's/a/b/' file.txt", []string{"file.txt"}},
+		{"sed 's/a/b/' file.txt", nil},
+		{"sudo tee /etc/hosts", []string{"/etc/hosts"}},
+	}
+
+	for _, tt := range tests {
+		script, err := Parse(tt.command)
+		if err != nil {
+			t.Errorf("Parse(%q) error: %v", tt.command, err)
+			continue
+		}
+		if got := script.WrittenFiles(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q).WrittenFiles() = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestOpaque(t *testing.T) {
+	tests := []struct {
+		command string
+		opaque  bool
+	}{
+		{"rm -rf /", false},
+		{"echo $HOME", false},
+		{"$(echo rm) -rf /", true},
+		{"`echo rm` -rf /", true},
+		{"$RM -rf /", true},
+		{"${RM} -rf /", true},
+		{"/bin/r? x", true},
+		{"/bin/r* x", true},
+		{"/bin/[r]m x", true},
+		{"{rm,-rf,/}", true},
+		{"[ -f x ]", false},
+		{"echo rm x | bash", true},
+		{"curl -s https://example.com/install.sh | sh", true},
+		{"bash <<< 'rm -rf /'", true},
+		{"bash <<EOF\nrm -rf /\nEOF", true},
+		{"sh < script.sh", true},
+		{"bash -s -- arg", true},
+		{"bash -", true},
+		{"bash script.sh", false},
+		{"bash -lc 'ls'", false},
+		{"bash -o pipefail -c 'ls'", false},
+		{"sudo $(echo rm) x", true},
+		{"echo x | xargs sh", true},
+	}
+
+	for _, tt := range tests {
+		script, err := Parse(tt.command)
+		if err != nil {
+			t.Errorf("Parse(%q) error: %v", tt.command, err)
+			continue
+		}
+		opaque := false
+		for _, cmd := range script.Commands {
+			opaque = opaque || cmd.Opaque()
+		}
+		if opaque != tt.opaque {
+			t.Errorf("Parse(%q) opaque = %v, want %v", tt.command, opaque, tt.opaque)
+		}
+	}
+}
+
+func TestHasPrefix(t *testing.T) {
+	script, err := Parse("/usr/bin/git status --short")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := script.Commands[0]
+
+	tests := []struct {
+		words []string
+		want  bool
+	}{
+		{[]string{"git"}, true},
+		{[]string{"git", "status"}, true},
+		{[]string{"/usr/bin/git", "status"}, true},
+		{[]string{"git", "push"}, false},
+		{[]string{"git", "status", "--short", "-v"}, false},
+		{nil, false},
+	}
+
+	for _, tt := range tests {
+		if got := cmd.HasPrefix(tt.words...); got != tt.want {
+			t.Errorf("HasPrefix(%q) = %v, want %v", tt.words, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+// Package stream assembles the partial-message StreamEvents emitted when
+// AgentOptions.IncludePartialMessages is enabled.
+//
+// The CLI forwards the raw Anthropic streaming events (message_start,
+// content_block_start, content_block_delta, content_block_stop,
+// message_delta and message_stop). An Assembler consumes them and yields
+// the individual text, thinking, tool input and citation deltas together
+// with an incrementally built AssistantMessage, whose text blocks collect
+// their citations.
+//
+// Example:
+//
+//	assembler := stream.NewAssembler()
+//	for msg := range client.ReceiveResponse() {
+//	    update := assembler.Push(msg)
+//	    if update == nil {
+//	        continue
+//	    }
+//	    if update.Delta != nil && update.Delta.Kind == stream.DeltaText {
+//	        fmt.Print(update.Delta.Text)
+//	    }
+//	}
+package stream
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/nabkey/claude-agent-sdk-go/internal/protocol"
+	"github.com/nabkey/claude-agent-sdk-go/types"
+)
+
+// DeltaKind identifies the kind of content carried by a Delta.
+type DeltaKind string
+
+const (
+	// DeltaText is a fragment of a text block.
+	DeltaText DeltaKind = "text"
+	// DeltaThinking is a fragment of a thinking block.
+	DeltaThinking DeltaKind = "thinking"
+	// DeltaSignature is a fragment of a thinking block's signature.
+	DeltaSignature DeltaKind = "signature"
+	// DeltaInputJSON is a fragment of a tool call's input as raw JSON.
+	DeltaInputJSON DeltaKind = "input_json"
+	// DeltaCitation is a citation attached to a text block.
+	DeltaCitation DeltaKind = "citation"
+)
+
+// Delta is an incremental change to one content block.
+type Delta struct {
+	// Kind identifies what Text holds.
+	Kind DeltaKind
+	// Index is the position of the content block in the message.
+	Index int
+	// Text is the fragment: text, thinking, signature or partial JSON.
+	Text string
+	// Citation is the citation of a DeltaCitation delta.
+	Citation map[string]any
+}
+
+// Update is the result of pushing one StreamEvent into an Assembler.
+type Update struct {
+	// Event is the streaming event type, such as "content_block_delta".
+	Event string
+	// ParentToolUseID identifies the subagent the message belongs to, if any.
+	ParentToolUseID *string
+	// Delta is set for content_block_delta events.
+	Delta *Delta
+	// BlockDone is set for content_block_stop events, when the block at
+	// Index is complete.
+	BlockDone bool
+	// Index is the content block index for content block events.
+	Index int
+	// Done is set for message_stop events; Message then returns the
+	// complete message.
+	Done bool
+
+	state *messageState
+}
+
+// Message returns a snapshot of the message being assembled, reflecting
+// all events pushed so far. Tool inputs are filled in once their block is
+// complete.
+func (u *Update) Message() *types.AssistantMessage {
+	if u.state == nil {
+		return nil
+	}
+	return u.state.snapshot()
+}
+
+// Assembler builds AssistantMessages from StreamEvents. Messages of the
+// main conversation and of each subagent are assembled independently, keyed
+// by ParentToolUseID. An Assembler is not safe for concurrent use.
+type Assembler struct {
+	messages map[string]*messageState
+}
+
+// NewAssembler creates an Assembler.
+func NewAssembler() *Assembler {
+	return &Assembler{messages: make(map[string]*messageState)}
+}
+
+// Push consumes a message. It returns nil for messages other than
+// StreamEvents and for events that do not belong to a message being
+// assembled.
+func (a *Assembler) Push(msg types.Message) *Update {
+	ev, ok := msg.(*types.StreamEvent)
+	if !ok || ev.Event == nil {
+		return nil
+	}
+
+	key := ""
+	if ev.ParentToolUseID != nil {
+		key = *ev.ParentToolUseID
+	}
+
+	eventType, _ := ev.Event["type"].(string)
+	update := &Update{Event: eventType, ParentToolUseID: ev.ParentToolUseID}
+
+	if eventType == "message_start" {
+		state := newMessageState(ev)
+		a.messages[key] = state
+		update.state = state
+		return update
+	}
+
+	state, ok := a.messages[key]
+	if !ok {
+		return nil
+	}
+	update.state = state
+
+	index := 0
+	if i, ok := ev.Event["index"].(float64); ok {
+		index = int(i)
+	}
+
+	switch eventType {
+	case "content_block_start":
+		data, _ := ev.Event["content_block"].(map[string]any)
+		state.start(index, protocol.ParseContentBlock(data))
+		update.Index = index
+
+	case "content_block_delta":
+		data, _ := ev.Event["delta"].(map[string]any)
+		update.Index = index
+		update.Delta = state.apply(index, data)
+
+	case "content_block_stop":
+		state.stop(index)
+		update.Index = index
+		update.BlockDone = true
+
+	case "message_delta":
+		if delta, ok := ev.Event["delta"].(map[string]any); ok {
+			if v, ok := delta["stop_reason"].(string); ok {
+				state.msg.StopReason = &v
+			}
+			if v, ok := delta["stop_sequence"].(string); ok {
+				state.msg.StopSequence = &v
+			}
+		}
+		if usage, ok := ev.Event["usage"].(map[string]any); ok {
+			state.updateUsage(usage)
+		}
+
+	case "message_stop":
+		delete(a.messages, key)
+		update.Done = true
+	}
+
+	return update
+}
+
+// messageState is a message under assembly.
+type messageState struct {
+	msg    types.AssistantMessage
+	blocks []*blockState
+}
+
+// blockState is a content block under assembly.
+type blockState struct {
+	start     types.ContentBlock
+	text      strings.Builder
+	signature strings.Builder
+	inputJSON strings.Builder
+	citations []map[string]any
+	done      bool
+}
+
+func newMessageState(ev *types.StreamEvent) *messageState {
+	state := &messageState{}
+	state.msg.ParentToolUseID = ev.ParentToolUseID
+	state.msg.SessionID = ev.SessionID
+	state.msg.UUID = ev.UUID
+
+	if message, ok := ev.Event["message"].(map[string]any); ok {
+		state.msg.ID, _ = message["id"].(string)
+		state.msg.Model, _ = message["model"].(string)
+		if usage, ok := message["usage"].(map[string]any); ok {
+			state.msg.Usage = protocol.ParseUsage(usage)
+		}
+	}
+	return state
+}
+
+func (s *messageState) block(index int) *blockState {
+	for len(s.blocks) <= index {
+		s.blocks = append(s.blocks, nil)
+	}
+	if s.blocks[index] == nil {
+		s.blocks[index] = &blockState{}
+	}
+	return s.blocks[index]
+}
+
+func (s *messageState) start(index int, block types.ContentBlock) {
+	s.block(index).start = block
+}
+
+func (s *messageState) apply(index int, data map[string]any) *Delta {
+	b := s.block(index)
+	delta := &Delta{Index: index}
+
+	switch data["type"] {
+	case "text_delta":
+		delta.Kind = DeltaText
+		delta.Text, _ = data["text"].(string)
+		b.text.WriteString(delta.Text)
+	case "thinking_delta":
+		delta.Kind = DeltaThinking
+		delta.Text, _ = data["thinking"].(string)
+		b.text.WriteString(delta.Text)
+	case "signature_delta":
+		delta.Kind = DeltaSignature
+		delta.Text, _ = data["signature"].(string)
+		b.signature.WriteString(delta.Text)
+	case "input_json_delta":
+		delta.Kind = DeltaInputJSON
+		delta.Text, _ = data["partial_json"].(string)
+		b.inputJSON.WriteString(delta.Text)
+	case "citations_delta":
+		delta.Kind = DeltaCitation
+		delta.Citation, _ = data["citation"].(map[string]any)
+		if delta.Citation != nil {
+			b.citations = append(b.citations, delta.Citation)
+		}
+	default:
+		return nil
+	}
+	return delta
+}
+
+func (s *messageState) stop(index int) {
+	s.block(index).done = true
+}
+
+// updateUsage applies a message_delta usage object. Counts in it are
+// cumulative for the message.
+func (s *messageState) updateUsage(data map[string]any) {
+	if s.msg.Usage == nil {
+		s.msg.Usage = &types.Usage{}
+	}
+	delta := protocol.ParseUsage(data)
+	if _, ok := data["input_tokens"]; ok {
+		s.msg.Usage.InputTokens = delta.InputTokens
+	}
+	if _, ok := data["output_tokens"]; ok {
+		s.msg.Usage.OutputTokens = delta.OutputTokens
+	}
+	if _, ok := data["cache_creation_input_tokens"]; ok {
+		s.msg.Usage.CacheCreationInputTokens = delta.CacheCreationInputTokens
+	}
+	if _, ok := data["cache_read_input_tokens"]; ok {
+		s.msg.Usage.CacheReadInputTokens = delta.CacheReadInputTokens
+	}
+	if delta.ServerToolUse != nil {
+		s.msg.Usage.ServerToolUse = delta.ServerToolUse
+	}
+}
+
+func (s *messageState) snapshot() *types.AssistantMessage {
+	msg := s.msg
+	if s.msg.Usage != nil {
+		usage := *s.msg.Usage
+		msg.Usage = &usage
+	}
+	msg.Content = make([]types.ContentBlock, 0, len(s.blocks))
+	for _, b := range s.blocks {
+		if b == nil || b.start == nil {
+			continue
+		}
+		msg.Content = append(msg.Content, b.snapshot())
+	}
+	return &msg
+}
+
+func (b *blockState) snapshot() types.ContentBlock {
+	switch start := b.start.(type) {
+	case *types.TextBlock:
+		block := &types.TextBlock{Text: start.Text + b.text.String()}
+		if len(start.Citations)+len(b.citations) > 0 {
+			block.Citations = append(append([]map[string]any{}, start.Citations...), b.citations...)
+		}
+		return block
+	case *types.ThinkingBlock:
+		return &types.ThinkingBlock{
+			Thinking:  start.Thinking + b.text.String(),
+			Signature: start.Signature + b.signature.String(),
+		}
+	case *types.ToolUseBlock:
+		c := *start
+		c.Input = b.input(start.Input)
+		return &c
+	case *types.ServerToolUseBlock:
+		c := *start
+		c.Input = b.input(start.Input)
+		return &c
+	default:
+		return b.start
+	}
+}
+
+// input returns the tool input decoded from the streamed JSON once the
+// block is complete, or the input the block started with.
+func (b *blockState) input(initial map[string]any) map[string]any {
+	if !b.done || b.inputJSON.Len() == 0 {
+		return initial
+	}
+	var input map[string]any
+	if err := json.Unmarshal([]byte(b.inputJSON.String()), &input); err != nil {
+		return initial
+	}
+	return input
+}
@@ -0,0 +1,235 @@
+package stream
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/nabkey/claude-agent-sdk-go/types"
+)
+
+// events builds StreamEvents from recorded raw events.
+func events(t *testing.T, parent *string, raw ...string) []types.Message {
+	t.Helper()
+	msgs := make([]types.Message, 0, len(raw))
+	for _, r := range raw {
+		var event map[string]any
+		if err := json.Unmarshal([]byte(r), &event); err != nil {
+			t.Fatalf("invalid event %s: %v", r, err)
+		}
+		msgs = append(msgs, &types.StreamEvent{SessionID: "session", Event: event, ParentToolUseID: parent})
+	}
+	return msgs
+}
+
+const messageStart = `{"type":"message_start","message":{"id":"msg_1","model":"claude-test","usage":{"input_tokens":10,"output_tokens":1}}}`
+
+func TestAssembler(t *testing.T) {
+	tests := []struct {
+		name       string
+		events     []string
+		content    []types.ContentBlock
+		stopReason string
+		usage      types.Usage
+	}{
+		{
+			name: "text",
+			events: []string{
+				messageStart,
+				`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello, "}}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"world"}}`,
+				`{"type":"content_block_stop","index":0}`,
+				`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":5}}`,
+				`{"type":"message_stop"}`,
+			},
+			content:    []types.ContentBlock{&types.TextBlock{Text: "Hello, world"}},
+			stopReason: "end_turn",
+			usage:      types.Usage{InputTokens: 10, OutputTokens: 5},
+		},
+		{
+			name: "thinking and tool input fragments",
+			events: []string{
+				messageStart,
+				`{"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Let me "}}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"look."}}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"sig"}}`,
+				`{"type":"content_block_stop","index":0}`,
+				`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"Bash","input":{}}}`,
+				`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":""}}`,
+				`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"comm"}}`,
+				`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"and\": \"ls -la\"}"}}`,
+				`{"type":"content_block_stop","index":1}`,
+				`{"type":"message_delta","delta":{"stop_reason":"tool_use","stop_sequence":null},"usage":{"output_tokens":42,"cache_read_input_tokens":7}}`,
+				`{"type":"message_stop"}`,
+			},
+			content: []types.ContentBlock{
+				&types.ThinkingBlock{Thinking: "Let me look.", Signature: "sig"},
+				&types.ToolUseBlock{ID: "toolu_1", Name: "Bash", Input: map[string]any{"command": "ls -la"}},
+			},
+			stopReason: "tool_use",
+			usage:      types.Usage{InputTokens: 10, OutputTokens: 42, CacheReadInputTokens: 7},
+		},
+		{
+			name: "citations",
+			events: []string{
+				messageStart,
+				`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":"","citations":[]}}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"citations_delta","citation":{"type":"char_location","cited_text":"Go is fast","document_index":0}}}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Go is fast."}}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"citations_delta","citation":{"type":"char_location","cited_text":"and simple","document_index":1}}}`,
+				`{"type":"content_block_stop","index":0}`,
+				`{"type":"message_stop"}`,
+			},
+			content: []types.ContentBlock{&types.TextBlock{
+				Text: "Go is fast.",
+				Citations: []map[string]any{
+					{"type": "char_location", "cited_text": "Go is fast", "document_index": 0.0},
+					{"type": "char_location", "cited_text": "and simple", "document_index": 1.0},
+				},
+			}},
+			usage: types.Usage{InputTokens: 10, OutputTokens: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		assembler := NewAssembler()
+		var last *Update
+		for _, msg := range events(t, nil, tt.events...) {
+			if update := assembler.Push(msg); update != nil {
+				last = update
+			}
+		}
+		if last == nil || !last.Done {
+			t.Errorf("%s: message was not completed", tt.name)
+			continue
+		}
+
+		msg := last.Message()
+		if msg.ID != "msg_1" || msg.Model != "claude-test" || msg.SessionID != "session" {
+			t.Errorf("%s: message header = %q %q %q", tt.name, msg.ID, msg.Model, msg.SessionID)
+		}
+		for i, block := range msg.Content {
+			if i >= len(tt.content) || !reflect.DeepEqual(block, tt.content[i]) {
+				data, _ := json.Marshal(block)
+				t.Errorf("%s: block %d = %s", tt.name, i, data)
+			}
+		}
+		if len(msg.Content) != len(tt.content) {
+			t.Errorf("%s: %d blocks, want %d", tt.name, len(msg.Content), len(tt.content))
+		}
+		var stopReason string
+		if msg.StopReason != nil {
+			stopReason = *msg.StopReason
+		}
+		if stopReason != tt.stopReason {
+			t.Errorf("%s: stop reason = %q, want %q", tt.name, stopReason, tt.stopReason)
+		}
+		usage := *msg.Usage
+		usage.Raw = nil
+		if !reflect.DeepEqual(usage, tt.usage) {
+			t.Errorf("%s: usage = %+v, want %+v", tt.name, usage, tt.usage)
+		}
+	}
+}
+
+func TestAssemblerDeltas(t *testing.T) {
+	assembler := NewAssembler()
+	msgs := events(t, nil,
+		messageStart,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"Read","input":{}}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"file_path\":"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"\"a.go\"}"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"unknown_delta"}}`,
+		`{"type":"content_block_stop","index":0}`,
+	)
+
+	var deltas []Delta
+	var inputs []map[string]any
+	for _, msg := range msgs {
+		update := assembler.Push(msg)
+		if update == nil {
+			t.Fatalf("Push(%v) = nil", msg)
+		}
+		if update.Delta != nil {
+			deltas = append(deltas, *update.Delta)
+		}
+		if block, ok := lastBlock(update.Message()).(*types.ToolUseBlock); ok {
+			inputs = append(inputs, block.Input)
+		}
+	}
+
+	want := []Delta{
+		{Kind: DeltaInputJSON, Text: `{"file_path":`},
+		{Kind: DeltaInputJSON, Text: `"a.go"}`},
+	}
+	if !reflect.DeepEqual(deltas, want) {
+		t.Errorf("deltas = %+v, want %+v", deltas, want)
+	}
+
+	// The input is only decoded once the block is complete
+	for _, input := range inputs[:len(inputs)-1] {
+		if len(input) != 0 {
+			t.Errorf("input before content_block_stop = %v, want empty", input)
+		}
+	}
+	if got := inputs[len(inputs)-1]; !reflect.DeepEqual(got, map[string]any{"file_path": "a.go"}) {
+		t.Errorf("input after content_block_stop = %v", got)
+	}
+}
+
+func TestAssemblerSubagents(t *testing.T) {
+	parent := "toolu_task"
+	assembler := NewAssembler()
+
+	// Events of the main conversation and of a subagent interleave
+	main := events(t, nil,
+		messageStart,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"main"}}`,
+		`{"type":"message_stop"}`,
+	)
+	sub := events(t, &parent,
+		messageStart,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"sub"}}`,
+		`{"type":"message_stop"}`,
+	)
+
+	var results []string
+	for i := range main {
+		for _, msg := range []types.Message{main[i], sub[i]} {
+			update := assembler.Push(msg)
+			if update == nil || !update.Done {
+				continue
+			}
+			text := update.Message().Content[0].(*types.TextBlock).Text
+			if update.ParentToolUseID != nil {
+				text = *update.ParentToolUseID + ":" + text
+			}
+			results = append(results, text)
+		}
+	}
+
+	if want := []string{"main", "toolu_task:sub"}; !reflect.DeepEqual(results, want) {
+		t.Errorf("messages = %q, want %q", results, want)
+	}
+
+	// Events after message_stop, or of an unknown message, are ignored
+	for _, msg := range events(t, &parent, `{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"late"}}`) {
+		if update := assembler.Push(msg); update != nil {
+			t.Errorf("Push after message_stop = %+v, want nil", update)
+		}
+	}
+	if update := assembler.Push(&types.ResultMessage{}); update != nil {
+		t.Errorf("Push(ResultMessage) = %+v, want nil", update)
+	}
+}
+
+func lastBlock(msg *types.AssistantMessage) types.ContentBlock {
+	if msg == nil || len(msg.Content) == 0 {
+		return nil
+	}
+	return msg.Content[len(msg.Content)-1]
+}
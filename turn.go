@@ -0,0 +1,229 @@
+package claude
+
+import (
+	"context"
+	"iter"
+	"sync"
+
+	"github.com/nabkey/claude-agent-sdk-go/errors"
+	"github.com/nabkey/claude-agent-sdk-go/types"
+)
+
+// Turn is the response to one query. It owns the messages Claude sends
+// for that query, up to and including the ResultMessage, and delivers each
+// of them exactly once: either through Messages, or through a
+// ReceiveResponse or ReceiveMessages call that claims the turn.
+//
+// Messages are buffered in memory until they are read, so a turn that is
+// never read does not block the connection or other turns. The Client keeps
+// the 64 most recent finished turns that were not read for ReceiveMessages
+// and ReceiveResponse, and forgets older ones; a forgotten turn can still
+// be read through its Turn, and its buffer is released once the Turn is no
+// longer referenced. Calling Wait or Messages claims a turn, which also
+// makes the Client forget it once it is finished.
+//
+// Example:
+//
+//	turn, err := client.SendQuery(ctx, "What is 2 + 2?")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	for msg := range turn.Messages() {
+//	    // Process messages...
+//	}
+//	result, err := turn.Wait(ctx)
+type Turn struct {
+	explicit bool // created by a query rather than by unsolicited messages
+	router   *router
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	buf      []types.Message
+	finished bool
+	result   *types.ResultMessage
+	err      error
+	done     chan struct{}
+
+	// Guarded by router.mu
+	claimed bool
+
+	streamOnce sync.Once
+	stream     chan types.Message
+}
+
+func newTurn(r *router, explicit bool) *Turn {
+	t := &Turn{
+		explicit: explicit,
+		router:   r,
+		done:     make(chan struct{}),
+	}
+	t.cond = sync.NewCond(&t.mu)
+	return t
+}
+
+// Messages returns the turn's messages. The channel is closed after the
+// ResultMessage, or when the connection ends. Every call returns the same
+// channel, and calling it claims the turn so that ReceiveResponse and
+// ReceiveMessages skip it.
+func (t *Turn) Messages() <-chan types.Message {
+	t.router.claimTurn(t)
+	return t.messages()
+}
+
+// Wait blocks until the turn completes and returns its ResultMessage. It
+// returns an error if the connection ended before the result arrived or
+// ctx is done. Waiting claims the turn; its messages remain available
+// through Messages.
+func (t *Turn) Wait(ctx context.Context) (*types.ResultMessage, error) {
+	t.router.claimTurn(t)
+
+	select {
+	case <-t.done:
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		return t.result, t.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// All returns an iterator over the turn's messages. ErrorMessages are
+// yielded as errors, as is the error that ended the turn early, if any.
+// Breaking out of the loop interrupts the turn if it is still running and
+// discards its remaining messages.
+//
+// Example:
+//
+//	for msg, err := range turn.All() {
+//	    if err != nil {
+//	        log.Printf("Error: %v", err)
+//	        continue
+//	    }
+//	    // Process msg...
+//	}
+func (t *Turn) All() iter.Seq2[types.Message, error] {
+	return func(yield func(types.Message, error) bool) {
+		t.router.claimTurn(t)
+		t.iterate(yield)
+	}
+}
+
+// Done returns a channel that is closed when the turn completes.
+func (t *Turn) Done() <-chan struct{} {
+	return t.done
+}
+
+// iterate yields the turn's messages and reports whether the loop ran to
+// completion.
+func (t *Turn) iterate(yield func(types.Message, error) bool) bool {
+	for msg := range t.messages() {
+		var more bool
+		if em, ok := msg.(*types.ErrorMessage); ok {
+			more = yield(nil, em.Err)
+		} else {
+			more = yield(msg, nil)
+		}
+		if !more {
+			t.cancel()
+			return false
+		}
+	}
+
+	t.mu.Lock()
+	err := t.err
+	t.mu.Unlock()
+
+	if err != nil {
+		return yield(nil, err)
+	}
+	return true
+}
+
+// cancel interrupts the turn if it is still running and discards the rest
+// of its messages.
+func (t *Turn) cancel() {
+	if !t.isFinished() && t.router.interrupt != nil {
+		go func() { _ = t.router.interrupt(context.Background()) }()
+	}
+	go func() {
+		for range t.stream {
+		}
+	}()
+}
+
+// messages starts forwarding the buffer to the turn's stream channel.
+func (t *Turn) messages() <-chan types.Message {
+	t.streamOnce.Do(func() {
+		t.stream = make(chan types.Message, 100)
+		go t.forward()
+	})
+	return t.stream
+}
+
+// forward moves buffered messages to the stream until the turn is finished
+// and the buffer is empty.
+func (t *Turn) forward() {
+	defer close(t.stream)
+
+	for {
+		t.mu.Lock()
+		for len(t.buf) == 0 && !t.finished {
+			t.cond.Wait()
+		}
+		if len(t.buf) == 0 {
+			t.mu.Unlock()
+			return
+		}
+		msg := t.buf[0]
+		t.buf[0] = nil
+		t.buf = t.buf[1:]
+		t.mu.Unlock()
+
+		t.stream <- msg
+	}
+}
+
+// push buffers a message, finishing the turn on its ResultMessage.
+func (t *Turn) push(msg types.Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.finished {
+		return
+	}
+	t.buf = append(t.buf, msg)
+	if result, ok := msg.(*types.ResultMessage); ok {
+		t.result = result
+		t.finishLocked(nil)
+	}
+	t.cond.Broadcast()
+}
+
+// finish ends the turn without a result.
+func (t *Turn) finish(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.finished {
+		t.finishLocked(err)
+		t.cond.Broadcast()
+	}
+}
+
+func (t *Turn) finishLocked(err error) {
+	t.finished = true
+	t.err = err
+	close(t.done)
+}
+
+// isFinished reports whether the turn has completed.
+func (t *Turn) isFinished() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.finished
+}
+
+// errConnectionClosed is the error of turns cut off by the end of the connection.
+func errConnectionClosed() error {
+	return errors.NewCLIConnectionError("Connection closed before the turn completed", nil)
+}
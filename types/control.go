@@ -80,8 +80,8 @@ type UserInputMessage struct {
 
 // UserInputInner is the inner content of a user input message.
 type UserInputInner struct {
-	Role    string `json:"role"` // "user"
-	Content string `json:"content"`
+	Role    string `json:"role"`    // "user"
+	Content any    `json:"content"` // string or []map[string]any of content blocks
 }
 
 // MCPJSONRPCRequest represents a JSON-RPC request to an MCP server.
@@ -31,6 +31,16 @@ const (
 	HookEventSubagentStop HookEvent = "SubagentStop"
 	// HookEventPreCompact fires before context compaction.
 	HookEventPreCompact HookEvent = "PreCompact"
+	// HookEventNotification fires when the CLI sends a notification.
+	HookEventNotification HookEvent = "Notification"
+	// HookEventSessionStart fires when a session starts, resumes, or is cleared.
+	HookEventSessionStart HookEvent = "SessionStart"
+	// HookEventSessionEnd fires when a session ends.
+	HookEventSessionEnd HookEvent = "SessionEnd"
+	// HookEventSubagentStart fires when a subagent starts.
+	HookEventSubagentStart HookEvent = "SubagentStart"
+	// HookEventPermissionRequest fires when a permission dialog would be shown.
+	HookEventPermissionRequest HookEvent = "PermissionRequest"
 )
 
 // PermissionBehavior defines how a permission decision is handled.
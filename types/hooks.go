@@ -1,6 +1,9 @@
 package types
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // HookInput is the interface for all hook input types.
 type HookInput interface {
@@ -83,9 +86,106 @@ type PreCompactHookInput struct {
 func (p *PreCompactHookInput) isHookInput()              {}
 func (p *PreCompactHookInput) GetHookEventName() HookEvent { return HookEventPreCompact }
 
+// NotificationHookInput is the input for Notification hook events.
+type NotificationHookInput struct {
+	BaseHookInput
+	HookEventName    HookEvent `json:"hook_event_name"` // "Notification"
+	Message          string    `json:"message"`
+	Title            *string   `json:"title,omitempty"`
+	NotificationType string    `json:"notification_type,omitempty"`
+}
+
+func (n *NotificationHookInput) isHookInput()                {}
+func (n *NotificationHookInput) GetHookEventName() HookEvent { return HookEventNotification }
+
+// SessionStartHookInput is the input for SessionStart hook events.
+type SessionStartHookInput struct {
+	BaseHookInput
+	HookEventName HookEvent `json:"hook_event_name"` // "SessionStart"
+	Source        string    `json:"source"`          // "startup", "resume", "clear" or "compact"
+	Model         string    `json:"model,omitempty"`
+}
+
+func (s *SessionStartHookInput) isHookInput()                {}
+func (s *SessionStartHookInput) GetHookEventName() HookEvent { return HookEventSessionStart }
+
+// SessionEndHookInput is the input for SessionEnd hook events.
+type SessionEndHookInput struct {
+	BaseHookInput
+	HookEventName HookEvent `json:"hook_event_name"` // "SessionEnd"
+	Reason        string    `json:"reason"`          // "clear", "logout", "prompt_input_exit" or "other"
+}
+
+func (s *SessionEndHookInput) isHookInput()                {}
+func (s *SessionEndHookInput) GetHookEventName() HookEvent { return HookEventSessionEnd }
+
+// SubagentStartHookInput is the input for SubagentStart hook events.
+type SubagentStartHookInput struct {
+	BaseHookInput
+	HookEventName HookEvent `json:"hook_event_name"` // "SubagentStart"
+	AgentID       string    `json:"agent_id"`
+	AgentType     string    `json:"agent_type"`
+}
+
+func (s *SubagentStartHookInput) isHookInput()                {}
+func (s *SubagentStartHookInput) GetHookEventName() HookEvent { return HookEventSubagentStart }
+
+// PermissionRequestHookInput is the input for PermissionRequest hook events.
+type PermissionRequestHookInput struct {
+	BaseHookInput
+	HookEventName         HookEvent          `json:"hook_event_name"` // "PermissionRequest"
+	ToolName              string             `json:"tool_name"`
+	ToolInput             map[string]any     `json:"tool_input"`
+	PermissionSuggestions []PermissionUpdate `json:"permission_suggestions,omitempty"`
+}
+
+func (p *PermissionRequestHookInput) isHookInput()                {}
+func (p *PermissionRequestHookInput) GetHookEventName() HookEvent { return HookEventPermissionRequest }
+
+// GenericHookInput is the input for hook events the SDK has no typed input
+// for yet. Data holds the complete raw input.
+type GenericHookInput struct {
+	BaseHookInput
+	HookEventName HookEvent      `json:"hook_event_name"`
+	Data          map[string]any `json:"-"`
+}
+
+func (g *GenericHookInput) isHookInput()                {}
+func (g *GenericHookInput) GetHookEventName() HookEvent { return g.HookEventName }
+
 // HookContext provides context for hook callbacks.
 type HookContext struct {
 	Signal any // Reserved for future abort signal support
+
+	// Go runs fn in the background after the callback has answered. fn's
+	// context is cancelled when the client closes, and Close waits at most
+	// five seconds for fn to return.
+	//
+	// The CLI does not accept hook output outside a hook response, so fn's
+	// output is delivered with the response of the next hook callback to
+	// fire, whatever its event. Events that do not take additional context
+	// turn it into a systemMessage shown to the user. If no further hook
+	// fires, the output is silently lost: to act on it reliably, handle it
+	// in OnAsyncHookResult, for example by sending a follow-up query. Go is
+	// nil when the callback is invoked outside a client.
+	Go func(fn AsyncHookFunc)
+}
+
+// AsyncHookFunc is background work started through HookContext.Go.
+type AsyncHookFunc func(ctx context.Context) (*HookOutput, error)
+
+// AsyncHookResult describes the completion of background hook work.
+type AsyncHookResult struct {
+	// Event is the hook event whose callback started the work.
+	Event HookEvent
+	// CallbackID is the SDK-assigned identifier of that callback.
+	CallbackID string
+	// ToolUseID is the tool use the hook fired for, if any.
+	ToolUseID *string
+	// Output is the output returned by the work, if any.
+	Output *HookOutput
+	// Err is the error returned by the work, if any.
+	Err error
 }
 
 // HookSpecificOutput is the interface for hook-specific output types.
@@ -119,6 +219,56 @@ type UserPromptSubmitHookSpecificOutput struct {
 
 func (u *UserPromptSubmitHookSpecificOutput) isHookSpecificOutput() {}
 
+// SessionStartHookSpecificOutput is the hook-specific output for SessionStart events.
+type SessionStartHookSpecificOutput struct {
+	HookEventName     string  `json:"hookEventName"` // "SessionStart"
+	AdditionalContext *string `json:"additionalContext,omitempty"`
+}
+
+func (s *SessionStartHookSpecificOutput) isHookSpecificOutput() {}
+
+// SubagentStartHookSpecificOutput is the hook-specific output for SubagentStart events.
+type SubagentStartHookSpecificOutput struct {
+	HookEventName     string  `json:"hookEventName"` // "SubagentStart"
+	AdditionalContext *string `json:"additionalContext,omitempty"`
+}
+
+func (s *SubagentStartHookSpecificOutput) isHookSpecificOutput() {}
+
+// NotificationHookSpecificOutput is the hook-specific output for Notification events.
+type NotificationHookSpecificOutput struct {
+	HookEventName     string  `json:"hookEventName"` // "Notification"
+	AdditionalContext *string `json:"additionalContext,omitempty"`
+}
+
+func (n *NotificationHookSpecificOutput) isHookSpecificOutput() {}
+
+// PermissionRequestDecision answers a permission request on behalf of the user.
+type PermissionRequestDecision struct {
+	Behavior           PermissionBehavior `json:"behavior"` // "allow" or "deny"
+	UpdatedInput       map[string]any     `json:"updatedInput,omitempty"`
+	UpdatedPermissions []PermissionUpdate `json:"updatedPermissions,omitempty"`
+	Message            string             `json:"message,omitempty"`
+	Interrupt          bool               `json:"interrupt,omitempty"`
+}
+
+// PermissionRequestHookSpecificOutput is the hook-specific output for PermissionRequest events.
+type PermissionRequestHookSpecificOutput struct {
+	HookEventName string                     `json:"hookEventName"` // "PermissionRequest"
+	Decision      *PermissionRequestDecision `json:"decision,omitempty"`
+}
+
+func (p *PermissionRequestHookSpecificOutput) isHookSpecificOutput() {}
+
+// GenericHookSpecificOutput is a hook-specific output for events without a
+// typed output. Fields are sent alongside hookEventName as-is.
+type GenericHookSpecificOutput struct {
+	HookEventName string
+	Fields        map[string]any
+}
+
+func (g *GenericHookSpecificOutput) isHookSpecificOutput() {}
+
 // HookOutput is the output from a hook callback.
 type HookOutput struct {
 	// Control fields
@@ -149,5 +299,42 @@ type HookMatcher struct {
 	// Hooks is a list of callback functions to execute when matched.
 	Hooks []HookCallback
 	// Timeout is the timeout in seconds for all hooks in this matcher.
+	// The SDK enforces it on each callback through a context deadline;
+	// when nil, zero or negative, 60 seconds, the CLI's own default, is
+	// used. The CLI is always sent the timeout plus a few seconds, so that
+	// it receives the timeout output before it stops waiting.
 	Timeout *float64
+	// TimeoutBehavior selects the output returned when a callback overruns
+	// its timeout. Defaults to HookTimeoutFailOpen.
+	TimeoutBehavior HookTimeoutBehavior
+}
+
+// HookTimeoutBehavior defines how an overrunning hook callback is answered.
+type HookTimeoutBehavior string
+
+const (
+	// HookTimeoutFailOpen answers with an empty output, as if the hook
+	// had not been registered.
+	HookTimeoutFailOpen HookTimeoutBehavior = "fail_open"
+	// HookTimeoutFailClosed answers with the most restrictive output for
+	// the event: PreToolUse and PermissionRequest are denied,
+	// UserPromptSubmit and PostToolUse are blocked, and other events stop
+	// the session.
+	HookTimeoutFailClosed HookTimeoutBehavior = "fail_closed"
+)
+
+// HookTimeoutEvent describes a hook callback that overran its timeout.
+type HookTimeoutEvent struct {
+	// Event is the hook event the callback was registered for.
+	Event HookEvent
+	// CallbackID is the SDK-assigned identifier of the callback.
+	CallbackID string
+	// ToolUseID is the tool use the hook fired for, if any.
+	ToolUseID *string
+	// Timeout is the deadline the callback exceeded.
+	Timeout time.Duration
+	// Behavior is the timeout behavior that was applied.
+	Behavior HookTimeoutBehavior
+	// Output is the output sent to the CLI in place of the callback's result.
+	Output *HookOutput
 }
@@ -3,7 +3,8 @@ package types
 import "encoding/json"
 
 // ContentBlock is a marker interface for message content blocks.
-// Implementations include TextBlock, ThinkingBlock, ToolUseBlock, and ToolResultBlock.
+// Implementations include TextBlock, ThinkingBlock, ToolUseBlock, ToolResultBlock,
+// the server-side tool blocks, and UnknownBlock for unrecognized types.
 type ContentBlock interface {
 	isContentBlock()
 }
@@ -11,6 +12,9 @@ type ContentBlock interface {
 // TextBlock represents a text content block.
 type TextBlock struct {
 	Text string `json:"text"`
+	// Citations are the sources cited by the text, as sent by the API,
+	// when citations are enabled on a document.
+	Citations []map[string]any `json:"citations,omitempty"`
 }
 
 func (t *TextBlock) isContentBlock() {}
@@ -35,14 +39,97 @@ func (t *ToolUseBlock) isContentBlock() {}
 // ToolResultBlock represents the result of a tool execution.
 type ToolResultBlock struct {
 	ToolUseID string `json:"tool_use_id"`
-	Content   any    `json:"content,omitempty"` // Can be string or []map[string]any
+	Content   any    `json:"content,omitempty"` // Can be string, []map[string]any or []ContentBlock
 	IsError   *bool  `json:"is_error,omitempty"`
 }
 
 func (t *ToolResultBlock) isContentBlock() {}
 
+// RedactedThinkingBlock represents thinking that was encrypted by safety
+// systems. Data must be passed back unchanged in multi-turn conversations.
+type RedactedThinkingBlock struct {
+	Data string `json:"data"`
+}
+
+func (t *RedactedThinkingBlock) isContentBlock() {}
+
+// ContentSource is the source of an image or document block.
+type ContentSource struct {
+	Type      string `json:"type"` // "base64", "url", "text" or "file"
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+	FileID    string `json:"file_id,omitempty"`
+}
+
+// ImageBlock represents an image.
+type ImageBlock struct {
+	Source ContentSource `json:"source"`
+}
+
+func (t *ImageBlock) isContentBlock() {}
+
+// DocumentBlock represents a document such as a PDF or plain text.
+type DocumentBlock struct {
+	Source  ContentSource `json:"source"`
+	Title   *string       `json:"title,omitempty"`
+	Context *string       `json:"context,omitempty"`
+}
+
+func (t *DocumentBlock) isContentBlock() {}
+
+// ServerToolUseBlock represents a tool invocation executed by the API
+// rather than the CLI, such as web search. MCP tool calls made by the API
+// ("mcp_tool_use") also set ServerName.
+type ServerToolUseBlock struct {
+	ID         string         `json:"id"`
+	Name       string         `json:"name"`
+	Input      map[string]any `json:"input"`
+	ServerName string         `json:"server_name,omitempty"`
+}
+
+func (t *ServerToolUseBlock) isContentBlock() {}
+
+// WebSearchResult is a single result of a server-side web search.
+type WebSearchResult struct {
+	URL              string  `json:"url"`
+	Title            string  `json:"title"`
+	EncryptedContent string  `json:"encrypted_content"`
+	PageAge          *string `json:"page_age,omitempty"`
+}
+
+// WebSearchToolResultBlock represents the result of a server-side web
+// search. ErrorCode is set instead of Results when the search failed.
+type WebSearchToolResultBlock struct {
+	ToolUseID string            `json:"tool_use_id"`
+	Results   []WebSearchResult `json:"-"`
+	ErrorCode string            `json:"-"`
+}
+
+func (t *WebSearchToolResultBlock) isContentBlock() {}
+
+// ServerToolResultBlock represents the result of any other server-side
+// tool, such as web_fetch_tool_result or code_execution_tool_result. Type
+// holds the block type and Content the result as received.
+type ServerToolResultBlock struct {
+	Type      string `json:"type"`
+	ToolUseID string `json:"tool_use_id"`
+	Content   any    `json:"content,omitempty"`
+}
+
+func (t *ServerToolResultBlock) isContentBlock() {}
+
+// UnknownBlock holds a content block of a type the SDK does not recognize.
+type UnknownBlock struct {
+	Type string         `json:"type"`
+	Raw  map[string]any `json:"-"`
+}
+
+func (t *UnknownBlock) isContentBlock() {}
+
 // Message is a marker interface for all message types.
-// Implementations include UserMessage, AssistantMessage, SystemMessage, and ResultMessage.
+// Implementations include UserMessage, AssistantMessage, SystemMessage, ResultMessage,
+// StreamEvent, UnknownMessage and ErrorMessage.
 type Message interface {
 	isMessage()
 }
@@ -51,40 +138,106 @@ type Message interface {
 type UserMessage struct {
 	Content         any     `json:"content"` // Can be string or []ContentBlock
 	ParentToolUseID *string `json:"parent_tool_use_id,omitempty"`
+	UUID            string  `json:"uuid,omitempty"`
+	SessionID       string  `json:"session_id,omitempty"`
 }
 
 func (m *UserMessage) isMessage() {}
 
 // AssistantMessage represents a response from Claude.
+//
+// The CLI may emit one AssistantMessage per content block of a single API
+// response. Such messages share the same ID and repeat its Usage, so
+// deduplicate by ID when accounting tokens per turn.
 type AssistantMessage struct {
 	Content         []ContentBlock         `json:"-"` // Custom unmarshal
 	Model           string                 `json:"model"`
 	ParentToolUseID *string                `json:"parent_tool_use_id,omitempty"`
 	Error           *AssistantMessageError `json:"error,omitempty"`
+	UUID            string                 `json:"uuid,omitempty"`
+	SessionID       string                 `json:"session_id,omitempty"`
+	ID              string                 `json:"id,omitempty"` // API message ID
+	StopReason      *string                `json:"stop_reason,omitempty"`
+	StopSequence    *string                `json:"stop_sequence,omitempty"`
+	Usage           *Usage                 `json:"usage,omitempty"`
 }
 
 func (m *AssistantMessage) isMessage() {}
 
 // SystemMessage represents a system message with metadata.
+//
+// Data always holds the raw message. For known subtypes the matching typed
+// field is populated as well: Init for "init" and CompactBoundary for
+// "compact_boundary".
 type SystemMessage struct {
 	Subtype string         `json:"subtype"`
 	Data    map[string]any `json:"data,omitempty"`
+
+	Init            *SystemInit      `json:"-"`
+	CompactBoundary *CompactBoundary `json:"-"`
 }
 
 func (m *SystemMessage) isMessage() {}
 
+// System message subtypes with typed payloads.
+const (
+	SystemSubtypeInit            = "init"
+	SystemSubtypeCompactBoundary = "compact_boundary"
+)
+
+// SystemInit is the payload of the "init" system message sent when a
+// session starts.
+type SystemInit struct {
+	SessionID         string            `json:"session_id"`
+	CWD               string            `json:"cwd"`
+	Model             string            `json:"model"`
+	Tools             []string          `json:"tools"`
+	MCPServers        []MCPServerStatus `json:"mcp_servers"`
+	PermissionMode    PermissionMode    `json:"permissionMode"`
+	SlashCommands     []string          `json:"slash_commands"`
+	APIKeySource      string            `json:"apiKeySource"`
+	Agents            []string          `json:"agents,omitempty"`
+	Skills            []string          `json:"skills,omitempty"`
+	Plugins           []InitPlugin      `json:"plugins,omitempty"`
+	OutputStyle       string            `json:"output_style,omitempty"`
+	ClaudeCodeVersion string            `json:"claude_code_version,omitempty"`
+}
+
+// MCPServerStatus reports the connection status of an MCP server.
+type MCPServerStatus struct {
+	Name   string `json:"name"`
+	Status string `json:"status"` // "connected", "failed", "needs-auth", "pending"
+}
+
+// InitPlugin describes a plugin loaded by the session.
+type InitPlugin struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// CompactBoundary is the payload of the "compact_boundary" system message
+// that marks where the conversation was compacted.
+type CompactBoundary struct {
+	Trigger   string `json:"trigger"` // "manual" or "auto"
+	PreTokens int    `json:"pre_tokens"`
+}
+
 // ResultMessage represents the final result of a query with cost and usage info.
 type ResultMessage struct {
-	Subtype          string         `json:"subtype"`
-	DurationMS       int            `json:"duration_ms"`
-	DurationAPIMS    int            `json:"duration_api_ms"`
-	IsError          bool           `json:"is_error"`
-	NumTurns         int            `json:"num_turns"`
-	SessionID        string         `json:"session_id"`
-	TotalCostUSD     *float64       `json:"total_cost_usd,omitempty"`
-	Usage            map[string]any `json:"usage,omitempty"`
-	Result           *string        `json:"result,omitempty"`
-	StructuredOutput any            `json:"structured_output,omitempty"`
+	Subtype          string   `json:"subtype"`
+	DurationMS       int      `json:"duration_ms"`
+	DurationAPIMS    int      `json:"duration_api_ms"`
+	IsError          bool     `json:"is_error"`
+	NumTurns         int      `json:"num_turns"`
+	SessionID        string   `json:"session_id"`
+	TotalCostUSD     *float64 `json:"total_cost_usd,omitempty"`
+	Usage            *Usage   `json:"usage,omitempty"`
+	Result           *string  `json:"result,omitempty"`
+	StructuredOutput any      `json:"structured_output,omitempty"`
+
+	// ModelUsage breaks usage and cost down by model. Like TotalCostUSD,
+	// it is cumulative for the CLI process that produced the result.
+	ModelUsage map[string]ModelUsage `json:"modelUsage,omitempty"`
 }
 
 func (m *ResultMessage) isMessage() {}
@@ -99,6 +252,29 @@ type StreamEvent struct {
 
 func (m *StreamEvent) isMessage() {}
 
+// UnknownMessage holds a message of a type the SDK does not recognize, so
+// that messages added by newer CLI versions are not lost.
+type UnknownMessage struct {
+	Type string         `json:"type"`
+	Raw  map[string]any `json:"-"`
+}
+
+func (m *UnknownMessage) isMessage() {}
+
+// ErrorMessage carries an error on a Client message stream, such as a typed
+// API error for an AssistantMessage whose Error is set.
+type ErrorMessage struct {
+	Err error
+}
+
+func (m *ErrorMessage) isMessage() {}
+
+// Error returns the message of the carried error.
+func (m *ErrorMessage) Error() string { return m.Err.Error() }
+
+// Unwrap returns the carried error.
+func (m *ErrorMessage) Unwrap() error { return m.Err }
+
 // RawMessage is used for parsing messages before determining their type.
 type RawMessage struct {
 	Type            string          `json:"type"`
@@ -0,0 +1,82 @@
+package types
+
+// Usage reports token usage for a turn or a message.
+type Usage struct {
+	InputTokens              int            `json:"input_tokens"`
+	OutputTokens             int            `json:"output_tokens"`
+	CacheCreationInputTokens int            `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int            `json:"cache_read_input_tokens"`
+	ServerToolUse            *ServerToolUse `json:"server_tool_use,omitempty"`
+	ServiceTier              string         `json:"service_tier,omitempty"`
+
+	// Raw holds the usage object as received from the CLI, including
+	// fields not modelled above.
+	Raw map[string]any `json:"-"`
+}
+
+// ServerToolUse counts server-side tool invocations.
+type ServerToolUse struct {
+	WebSearchRequests int `json:"web_search_requests"`
+	WebFetchRequests  int `json:"web_fetch_requests"`
+}
+
+// ModelUsage is the usage and cost attributed to a single model.
+type ModelUsage struct {
+	InputTokens              int     `json:"inputTokens"`
+	OutputTokens             int     `json:"outputTokens"`
+	CacheReadInputTokens     int     `json:"cacheReadInputTokens"`
+	CacheCreationInputTokens int     `json:"cacheCreationInputTokens"`
+	WebSearchRequests        int     `json:"webSearchRequests"`
+	CostUSD                  float64 `json:"costUSD"`
+	ContextWindow            int     `json:"contextWindow,omitempty"`
+}
+
+// TotalInputTokens returns input tokens including cache reads and writes.
+func (u *Usage) TotalInputTokens() int {
+	return u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+}
+
+// Add accumulates other into u. Raw is not merged.
+func (u *Usage) Add(other *Usage) {
+	if other == nil {
+		return
+	}
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.CacheCreationInputTokens += other.CacheCreationInputTokens
+	u.CacheReadInputTokens += other.CacheReadInputTokens
+	if other.ServerToolUse != nil {
+		if u.ServerToolUse == nil {
+			u.ServerToolUse = &ServerToolUse{}
+		}
+		u.ServerToolUse.WebSearchRequests += other.ServerToolUse.WebSearchRequests
+		u.ServerToolUse.WebFetchRequests += other.ServerToolUse.WebFetchRequests
+	}
+}
+
+// Add accumulates other into m. ContextWindow keeps the larger value.
+func (m *ModelUsage) Add(other ModelUsage) {
+	m.InputTokens += other.InputTokens
+	m.OutputTokens += other.OutputTokens
+	m.CacheReadInputTokens += other.CacheReadInputTokens
+	m.CacheCreationInputTokens += other.CacheCreationInputTokens
+	m.WebSearchRequests += other.WebSearchRequests
+	m.CostUSD += other.CostUSD
+	if other.ContextWindow > m.ContextWindow {
+		m.ContextWindow = other.ContextWindow
+	}
+}
+
+// Sub returns m minus other. It is used to turn cumulative per-model usage
+// into per-turn usage.
+func (m ModelUsage) Sub(other ModelUsage) ModelUsage {
+	return ModelUsage{
+		InputTokens:              m.InputTokens - other.InputTokens,
+		OutputTokens:             m.OutputTokens - other.OutputTokens,
+		CacheReadInputTokens:     m.CacheReadInputTokens - other.CacheReadInputTokens,
+		CacheCreationInputTokens: m.CacheCreationInputTokens - other.CacheCreationInputTokens,
+		WebSearchRequests:        m.WebSearchRequests - other.WebSearchRequests,
+		CostUSD:                  m.CostUSD - other.CostUSD,
+		ContextWindow:            m.ContextWindow,
+	}
+}